@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"main/internal/report"
+	"main/internal/voter"
+	"main/internal/voterBackup"
+	"main/internal/voterConfirmer"
+	"main/internal/voterDeleter"
+	"main/internal/voterParser"
+	"main/internal/voterReconciler"
+	"main/internal/voterRestorer"
+	"main/internal/voterStatus"
+	"main/pkg/types"
+	"main/pkg/util"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type accountAction struct {
+	name    string
+	title   string
+	run     func(ctx context.Context, accountData types.AccountData, accountProxy string, accountReport *report.AccountReport) error
+	prepare func(options cliOptions) error
+	dryRun  bool // the action honours -dry-run
+	// destructive actions show a dry-run snapshot and ask for confirmation unless -yes is given
+	destructive bool
+}
+
+type toolCommand struct {
+	name  string
+	title string
+	run   func(options cliOptions) error
+}
+
+type cliOptions struct {
+	action       *accountAction
+	tool         *toolCommand
+	threads      int
+	configDir    string
+	accountsPath string
+	proxiesPath  string
+	roundID      string
+	reportDir    string
+	exportPath   string
+	keystorePath string
+	passwordFile string
+	signerCmd    string
+	insecureTLS  bool
+	planPath     string
+	dryRun       bool
+	resumeID     string
+	stateFile    string
+	backupPath   string
+	backupDir    string
+	catalog      catalogOptions
+	deletion     deletionOptions
+	yes          bool
+}
+
+type deletionOptions struct {
+	projects  string
+	voteState string
+	voteCount string
+}
+
+var accountActions = []accountAction{
+	{"parse", "Parse Accounts Votes", voterParser.ParseVotes, prepareParser, false, false},
+	{"vote", "Projects Voter", voter.DoVotes, prepareVoter, true, false},
+	{"delete", "Votes Deleter", voterDeleter.DeleteVotes, prepareDeleter, true, true},
+	{"status", "Ballot Status", voterStatus.ShowStatus, nil, false, false},
+	{"confirm", "Confirm Pending Votes", voterConfirmer.ConfirmVotes, nil, true, false},
+	{"reconcile", "Reconcile Ballots With Plan", voterReconciler.Reconcile, prepareReconciler, true, true},
+	{"backup", "Backup Ballots", voterBackup.BackupBallots, nil, false, false},
+	{"restore", "Restore Ballots From Backup", voterRestorer.RestoreVotes, prepareRestorer, true, true},
+}
+
+func prepareParser(options cliOptions) error {
+	return voterParser.InitOutputs("accounts_with_votes.txt", options.exportPath)
+}
+
+func prepareVoter(options cliOptions) error {
+	if options.planPath != "" {
+		return voter.InitPlan(options.planPath, options.resumeID != "")
+	}
+
+	return voter.InitDistribution()
+}
+
+func prepareDeleter(options cliOptions) error {
+	minVotes, maxVotes, err := parseBounds(options.deletion.voteCount)
+	if err != nil {
+		return fmt.Errorf("-vote-count: %v", err)
+	}
+
+	return voterDeleter.InitSelection(voterDeleter.Selection{
+		Projects: splitList(options.deletion.projects),
+		State:    strings.ToLower(strings.TrimSpace(options.deletion.voteState)),
+		MinVotes: minVotes,
+		MaxVotes: maxVotes,
+	})
+}
+
+func prepareReconciler(options cliOptions) error {
+	return voterReconciler.InitPlan(options.planPath)
+}
+
+func prepareRestorer(options cliOptions) error {
+	return voterRestorer.InitBackup(options.backupPath)
+}
+
+var toolCommands = []toolCommand{
+	{"vault", "Encrypt Accounts File Into A Keystore Directory", runVault},
+	{"projects", "List Round Projects (Table / JSON / CSV)", runProjects},
+}
+
+func findTool(name string) *toolCommand {
+	for i := range toolCommands {
+		if toolCommands[i].name == name {
+			return &toolCommands[i]
+		}
+	}
+
+	return nil
+}
+
+func findAction(name string) *accountAction {
+	for i := range accountActions {
+		if accountActions[i].name == name {
+			return &accountActions[i]
+		}
+	}
+
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\nCommands:\n", filepath.Base(os.Args[0]))
+
+	for _, action := range accountActions {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", action.name, action.title)
+	}
+
+	for _, tool := range toolCommands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", tool.name, tool.title)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nWithout a command the interactive menu is shown.\n\nFlags:\n")
+}
+
+// parseArgs returns options with a nil action when the interactive menu should be used
+func parseArgs(args []string) (cliOptions, error) {
+	var options cliOptions
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if args[0] == "help" {
+			args = []string{"-h"}
+		} else {
+			options.action = findAction(args[0])
+			options.tool = findTool(args[0])
+
+			if options.action == nil && options.tool == nil {
+				usage()
+				return options, fmt.Errorf("unknown command: %s", args[0])
+			}
+
+			args = args[1:]
+		}
+	}
+
+	flags := flag.NewFlagSet("retro9000_voter", flag.ContinueOnError)
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+
+	flags.IntVar(&options.threads, "threads", 0, "number of accounts processed in parallel")
+	flags.StringVar(&options.configDir, "config", "config", "config directory")
+	flags.StringVar(&options.accountsPath, "accounts", "", "accounts file (default <config>/accounts.txt)")
+	flags.StringVar(&options.proxiesPath, "proxies", "", "proxies file (default <config>/proxies.txt)")
+	flags.StringVar(&options.roundID, "round", "", "round ID (overrides const.json)")
+	flags.StringVar(&options.reportDir, "report-dir", "reports", "directory for JSON/CSV run reports")
+	flags.StringVar(&options.exportPath, "export-accounts", "",
+		"parse: write private keys of accounts with votes to this file (0600, no duplicates)")
+	flags.StringVar(&options.keystorePath, "keystore", "",
+		"V3 keystore file or directory to load accounts from (vault: output directory, default <config>/keystore)")
+	flags.StringVar(&options.passwordFile, "password-file", "",
+		"file with the keystore passphrase (otherwise $"+util.KeystorePasswordEnv+" or a prompt)")
+
+	flags.StringVar(&options.signerCmd, "signer", "",
+		"external signer command, its accounts are added to the list (JSON-RPC eth_accounts / personal_sign over stdin/stdout)")
+
+	flags.BoolVar(&options.insecureTLS, "insecure-skip-tls-verify", false,
+		"do not verify TLS certificates: any proxy can read and change requests, signatures and session cookies")
+
+	flags.StringVar(&options.planPath, "plan", "",
+		"vote, reconcile: YAML or CSV allocation plan (account,project,votes), for vote it replaces the random distribution")
+
+	flags.BoolVar(&options.dryRun, "dry-run", false,
+		"vote, delete, confirm, reconcile, restore: read the ballots and report the requests that would be sent, without sending them")
+
+	flags.StringVar(&options.resumeID, "resume", "",
+		"continue the run with this ID: completed accounts are skipped, the rest continue from their last step")
+	flags.StringVar(&options.stateFile, "state-file", "runs.db", "file that keeps the progress of every run")
+
+	flags.StringVar(&options.backupPath, "backup", "", "restore: ballot backup file to restore")
+	flags.StringVar(&options.backupDir, "backup-dir", "backups",
+		"directory for ballot backups, written by backup and before delete, reconcile and restore change a ballot")
+
+	flags.StringVar(&options.catalog.format, "format", "table", "projects: output format (table, json or csv)")
+	flags.StringVar(&options.catalog.outPath, "out", "", "projects: write to this file instead of stdout")
+	flags.StringVar(&options.catalog.sortBy, "sort", "rank",
+		"projects: sort by rank, name, status, voters or votes, prefix with - for descending order")
+	flags.StringVar(&options.catalog.categories, "category", "", "projects: comma separated categories to include")
+	flags.StringVar(&options.catalog.excludeCategories, "exclude-category", "", "projects: comma separated categories to skip")
+	flags.StringVar(&options.catalog.statuses, "status", "", "projects: comma separated statuses to include")
+	flags.StringVar(&options.catalog.rankRange, "rank", "", "projects: rank range like 1-50, 10- or -100")
+	flags.StringVar(&options.catalog.votersRange, "voters", "", "projects: unique voters range like 10-500")
+	flags.StringVar(&options.catalog.votesRange, "votes", "", "projects: total votes range like 1000-")
+	flags.StringVar(&options.catalog.match, "match", "", "projects: comma separated project IDs or name regexes to include")
+	flags.StringVar(&options.catalog.exclude, "exclude", "", "projects: comma separated project IDs or name regexes to skip")
+
+	flags.StringVar(&options.deletion.projects, "only-projects", "",
+		"delete: comma separated project IDs or names, other votes are kept")
+	flags.StringVar(&options.deletion.voteState, "vote-state", "", "delete: only confirmed or unconfirmed votes")
+	flags.StringVar(&options.deletion.voteCount, "vote-count", "",
+		"delete: only votes with this count, like -10 (at most 10), 50- (at least 50) or 5-20")
+	flags.BoolVar(&options.yes, "yes", false, "delete, reconcile, restore: skip the snapshot and the confirmation prompt")
+
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+
+	if flags.NArg() > 0 {
+		return options, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	if options.accountsPath == "" {
+		options.accountsPath = filepath.Join(options.configDir, "accounts.txt")
+	}
+
+	if options.proxiesPath == "" {
+		options.proxiesPath = filepath.Join(options.configDir, "proxies.txt")
+	}
+
+	if options.dryRun && options.action != nil && !options.action.dryRun {
+		return options, fmt.Errorf("-dry-run is not supported by %s", options.action.name)
+	}
+
+	if options.dryRun && options.resumeID != "" {
+		return options, fmt.Errorf("-dry-run cannot be combined with -resume")
+	}
+
+	if options.action != nil && options.threads <= 0 {
+		options.threads = 1
+	}
+
+	return options, nil
+}
@@ -2,20 +2,31 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"github.com/ethereum/go-ethereum/common"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
 	"io"
-	"main/internal/voter"
-	"main/internal/voterDeleter"
-	"main/internal/voterParser"
+	"main/internal/ballotBackup"
+	"main/internal/report"
+	"main/internal/retroActions"
+	"main/internal/runState"
+	"main/internal/sessionCache"
+	util2 "main/internal/util"
 	"main/pkg/global"
+	"main/pkg/signer"
 	"main/pkg/types"
 	"main/pkg/util"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 )
 
 func initLog() {
@@ -26,47 +37,121 @@ func initLog() {
 	})
 }
 
+type runSummary struct {
+	total       int
+	succeeded   int
+	failed      int
+	interrupted int
+	notStarted  int
+	skipped     int
+}
+
+// runAccount keeps a keystore key decrypted only while its account runs
+func runAccount(
+	ctx context.Context,
+	action *accountAction,
+	accountData types.AccountData,
+	accountReport *report.AccountReport,
+) error {
+	if unlocker, ok := accountData.Signer.(signer.Unlocker); ok {
+		if err := unlocker.Unlock(); err != nil {
+			return fmt.Errorf("%s | %v", accountData.AccountAddress.String(), err)
+		}
+
+		defer unlocker.Lock()
+	}
+
+	return action.run(ctx, accountData, util.ProxiesCycler.Next(), accountReport)
+}
+
 func processAccounts(
+	ctx context.Context,
 	threads int,
-	userAction int,
-) {
+	action *accountAction,
+	runReport *report.Report,
+) runSummary {
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, threads)
-	errChan := make(chan error, len(global.AccountsList))
+	resultsChan := make(chan error, len(global.AccountsList))
+	summary := runSummary{total: len(global.AccountsList)}
+
+	for i, account := range global.AccountsList {
+		if runState.Get(account.AccountAddress.String()).Passed(runState.StepDone) {
+			runReport.Accounts[i].Status = report.StatusSkipped
+			summary.skipped++
+			log.Printf("%s | Already Completed In This Run, Skipping", account.AccountAddress.String())
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
 
-	for _, account := range global.AccountsList {
 		wg.Add(1)
-		sem <- struct{}{}
 
-		go func(acc types.AccountData) {
+		go func(acc types.AccountData, accountReport *report.AccountReport) {
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			var err error
-			if userAction == 1 {
-				err = voterParser.ParseVotes(acc, util.ProxiesCycler.Next())
-			} else if userAction == 2 {
-				err = voter.DoVotes(acc, util.ProxiesCycler.Next())
-			} else if userAction == 3 {
-				err = voterDeleter.DeleteVotes(acc, util.ProxiesCycler.Next())
+			accountReport.Start()
+			err := runAccount(ctx, action, acc, accountReport)
+
+			switch {
+			case err == nil:
+				accountReport.Finish(report.StatusSucceeded, nil)
+			case errors.Is(err, util2.ErrInterrupted):
+				accountReport.Finish(report.StatusInterrupted, err)
+			default:
+				accountReport.Finish(report.StatusFailed, err)
 			}
 
-			if err != nil {
-				errChan <- err
+			if stateErr := runState.Finish(acc.AccountAddress.String(), err); stateErr != nil {
+				log.Errorf("%v", stateErr)
 			}
-		}(account)
+
+			resultsChan <- err
+		}(account, &runReport.Accounts[i])
 	}
 
 	go func() {
 		wg.Wait()
-		close(errChan)
+		close(resultsChan)
 	}()
 
-	for err := range errChan {
-		if err != nil {
+	for err := range resultsChan {
+		switch {
+		case err == nil:
+			summary.succeeded++
+		case errors.Is(err, util2.ErrInterrupted):
+			summary.interrupted++
+			log.Warnf("%v", err)
+		default:
+			summary.failed++
 			log.Errorf("%v", err)
 		}
 	}
+
+	summary.notStarted = summary.total - summary.succeeded - summary.failed - summary.interrupted - summary.skipped
+
+	return summary
+}
+
+func handleSignals(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	<-signals
+	log.Warnf("Shutdown Requested, Finishing Current Steps.. Press Ctrl+C Again To Force Exit")
+	cancel()
+
+	<-signals
+	log.Warnf("Forced Exit")
+	os.Exit(1)
 }
 
 func inputUser(prompt string) string {
@@ -84,18 +169,199 @@ func inputUser(prompt string) string {
 	return input
 }
 
-func handlePanic() {
+func handlePanic(interactive bool) {
 	if r := recover(); r != nil {
 		log.Printf("Unexpected Error: %v", r)
-		fmt.Println("Press Enter to Exit..")
-		_, _ = fmt.Scanln()
+		if interactive {
+			fmt.Println("Press Enter to Exit..")
+			_, _ = fmt.Scanln()
+		}
 		os.Exit(1)
 	}
 }
 
+func chooseAction() *accountAction {
+	menu := "\n\n"
+	for i, action := range accountActions {
+		menu += fmt.Sprintf("%d. %s\n", i+1, action.title)
+	}
+
+	inputData := inputUser(menu + "Enter Your Action: ")
+	userAction, err := strconv.Atoi(inputData)
+
+	if err != nil || userAction < 1 || userAction > len(accountActions) {
+		log.Panicf("Wrong User Action Number: %s", inputData)
+	}
+
+	return &accountActions[userAction-1]
+}
+
+// confirmDestructiveRun runs the action in dry run, saves and shows what it would change and asks to continue
+func confirmDestructiveRun(
+	ctx context.Context,
+	threads int,
+	action *accountAction,
+	reportDir string,
+	addresses []string,
+) (bool, error) {
+	log.Printf("Collecting A Snapshot Of What %s Would Change..", action.title)
+
+	global.DryRun = true
+	snapshotReport := report.New(action.name, global.Const.RoundID, true, addresses)
+	summary := processAccounts(ctx, threads, action, snapshotReport)
+	global.DryRun = false
+
+	if ctx.Err() != nil {
+		return false, errors.New("snapshot interrupted, nothing was changed")
+	}
+
+	jsonPath, _, err := snapshotReport.Write(reportDir)
+	if err != nil {
+		return false, fmt.Errorf("error writing snapshot: %v", err)
+	}
+
+	var plannedRequests, affectedAccounts int
+	for _, accountReport := range snapshotReport.Accounts {
+		if len(accountReport.Planned) > 0 {
+			affectedAccounts++
+			plannedRequests += len(accountReport.Planned)
+		}
+	}
+
+	if summary.failed > 0 {
+		log.Warnf("Snapshot Failed For %d Accounts, They Are Not Included", summary.failed)
+	}
+
+	if plannedRequests == 0 {
+		log.Printf("Nothing To Change, Snapshot Saved To %s", jsonPath)
+		return false, nil
+	}
+
+	log.Warnf("%s Will Send %d Requests On %d Accounts, Snapshot Saved To %s",
+		action.title, plannedRequests, affectedAccounts, jsonPath)
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, errors.New("confirmation required: rerun with -yes to proceed without a terminal")
+	}
+
+	if answer := inputUser("Type 'yes' To Continue: "); answer != "yes" {
+		log.Printf("Cancelled, Nothing Was Changed")
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// externalSigner is the signer process started for -signer, closed when the run ends
+var externalSigner *signer.External
+
+// remaining is how many accounts a source may still add, 0 when there is no limit
+func remaining(limit int, accountsList []types.AccountData) int {
+	if limit == 0 {
+		return 0
+	}
+
+	return limit - len(accountsList)
+}
+
+// loadAccounts reads accounts.txt, the keystore and the external signer in this order,
+// limit stops after that many accounts (0 - no limit) without touching the remaining sources
+func loadAccounts(options cliOptions, limit int) ([]types.AccountData, error) {
+	var accountsList []types.AccountData
+
+	accountsListString, err := util.ReadFileByRows(options.accountsPath)
+
+	otherSources := options.keystorePath != "" || options.signerCmd != ""
+
+	if err != nil && (!otherSources || !errors.Is(err, os.ErrNotExist)) {
+		return nil, fmt.Errorf("Error Reading Accounts List File: %w", err)
+	}
+
+	if err == nil {
+		accountsList, err = util.GetAccounts(accountsListString, global.Settings.Derivation, limit)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.keystorePath != "" && (limit == 0 || len(accountsList) < limit) {
+		passphrase, err := util.ReadPassphrase(options.passwordFile, "Keystore Passphrase: ", false)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keystoreAccounts, err := util.GetKeystoreAccounts(options.keystorePath, passphrase, remaining(limit, accountsList))
+
+		if err != nil {
+			return nil, err
+		}
+
+		accountsList = append(accountsList, keystoreAccounts...)
+	}
+
+	if options.signerCmd != "" && (limit == 0 || len(accountsList) < limit) {
+		externalSigner, err = signer.StartExternal(options.signerCmd)
+
+		if err != nil {
+			return nil, err
+		}
+
+		signers, err := externalSigner.Accounts()
+
+		if err != nil {
+			return nil, fmt.Errorf("error when listing external signer accounts: %v", err)
+		}
+
+		if limit > 0 && len(signers) > limit-len(accountsList) {
+			signers = signers[:limit-len(accountsList)]
+		}
+
+		for _, accountSigner := range signers {
+			accountsList = append(accountsList, types.AccountData{
+				AccountAddress: accountSigner.Address(),
+				Signer:         accountSigner,
+			})
+		}
+
+		log.Printf("Loaded %d Accounts From External Signer", len(signers))
+	}
+
+	uniqueAccounts := make([]types.AccountData, 0, len(accountsList))
+	seenAddresses := map[common.Address]struct{}{}
+
+	for _, account := range accountsList {
+		if _, ok := seenAddresses[account.AccountAddress]; ok {
+			log.Warnf("%s | Duplicate Account Skipped", account.AccountAddress.String())
+			continue
+		}
+
+		seenAddresses[account.AccountAddress] = struct{}{}
+		uniqueAccounts = append(uniqueAccounts, account)
+	}
+
+	return uniqueAccounts, nil
+}
+
 func main() {
+	options, err := parseArgs(os.Args[1:])
+
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	os.Exit(run(options))
+}
+
+func run(options cliOptions) int {
 	// init log
-	var inputData string
+	interactive := options.action == nil && options.tool == nil
 	initLog()
 
 	wr, err := os.OpenFile(filepath.Join("log.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
@@ -114,54 +380,179 @@ func main() {
 	mw := io.MultiWriter(os.Stdout, wr)
 	log.SetOutput(mw)
 
-	defer handlePanic()
+	defer handlePanic(interactive)
 
 	// init proxies
-	err = util.InitProxies(filepath.Join("config", "proxies.txt"))
+	err = util.InitProxies(options.proxiesPath)
 	if err != nil {
 		log.Panicf("Error initializing proxies: %v", err)
 	}
 	// --> init
-	err = util.ReadJsonFile(filepath.Join("config", "const.json"),
+	err = util.ReadJsonFile(filepath.Join(options.configDir, "const.json"),
 		&global.Const)
 
 	if err != nil {
 		log.Panicf("Error reading const.json: %v", err)
 	}
 
-	accountsListString, err := util.ReadFileByRows(filepath.Join("config", "accounts.txt"))
+	if options.roundID != "" {
+		global.Const.RoundID = options.roundID
+	}
+
+	err = util.ReadJsonFile(filepath.Join(options.configDir, "settings.json"),
+		&global.Settings)
 
 	if err != nil {
-		log.Panicf("Error Reading Accounts List File: %v", err.Error())
+		log.Panicf("Error reading settings.json: %v", err)
 	}
 
-	global.AccountsList, err = util.GetAccounts(accountsListString)
+	if options.insecureTLS {
+		log.Warnf("!!! TLS CERTIFICATE VERIFICATION IS DISABLED (-insecure-skip-tls-verify) !!!")
+		log.Warnf("!!! Any Proxy Or Network Hop Can Read And Change Requests, Signatures And Session Cookies !!!")
+	}
+
+	if err = util2.InitTLS(global.Settings.TLS, retroActions.BaseURL(), options.insecureTLS); err != nil {
+		log.Panicf("Error Setting Up TLS: %v", err)
+	}
+
+	if global.Settings.SessionCache.Enabled {
+		if err = sessionCache.Init(global.Settings.SessionCache.Path); err != nil {
+			log.Panicf("Error Opening Session Cache: %v", err)
+		}
+	}
+
+	if options.tool != nil {
+		if err = options.tool.run(options); err != nil {
+			log.Errorf("%v", err)
+			return 1
+		}
+
+		return 0
+	}
+
+	global.AccountsList, err = loadAccounts(options, 0)
+
+	if externalSigner != nil {
+		defer externalSigner.Close()
+	}
 
 	if err != nil {
-		log.Panicf(err.Error())
+		log.Panicf("%v", err)
 	}
 
 	fmt.Printf("Successfully Loaded %d Accounts / %d Proxies", len(global.AccountsList), len(util.Proxies))
 
-	inputData = inputUser("\n\n1. Parse Accounts Votes\n2. Projects Voter\n3. Votes Deleter\nEnter Your Action: ")
+	action := options.action
+	if interactive {
+		action = chooseAction()
+	} else {
+		fmt.Println()
+	}
 
-	userAction, err := strconv.Atoi(inputData)
+	if options.dryRun {
+		if !action.dryRun {
+			log.Panicf("Dry Run Is Not Supported By %s", action.title)
+		}
 
-	if err != nil {
-		log.Panicf("Wrong User Action Number: %s", inputData)
+		global.DryRun = true
+		log.Warnf("Dry Run: Ballots Will Only Be Read, Planned Requests Are Logged And Saved To The Report")
+	}
+
+	if action.prepare != nil {
+		if err = action.prepare(options); err != nil {
+			log.Panicf("Error Preparing %s: %v", action.title, err)
+		}
 	}
 
-	inputData = inputUser("Threads: ")
-	threads, err := strconv.Atoi(inputData)
+	threads := options.threads
+	if threads <= 0 {
+		inputData := inputUser("Threads: ")
+		threads, err = strconv.Atoi(inputData)
 
-	if err != nil {
-		log.Panicf("Wrong Threads Number: %s", inputData)
+		if err != nil || threads <= 0 {
+			log.Panicf("Wrong Threads Number: %s", inputData)
+		}
 	}
 
 	fmt.Println()
 
-	processAccounts(threads, userAction)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go handleSignals(cancel)
+
+	addresses := make([]string, len(global.AccountsList))
+	for i, account := range global.AccountsList {
+		addresses[i] = account.AccountAddress.String()
+	}
+
+	if action.destructive && !global.DryRun && !options.yes {
+		proceed, err := confirmDestructiveRun(ctx, threads, action, options.reportDir, addresses)
+
+		if err != nil {
+			log.Panicf("%v", err)
+		}
+
+		if !proceed {
+			return 0
+		}
+	}
+
+	runReport := report.New(action.name, global.Const.RoundID, global.DryRun, addresses)
+
+	// dry run changes nothing, so there is no progress to keep
+	if !global.DryRun {
+		runInfo, err := runState.Open(options.stateFile, options.resumeID, action.name, global.Const.RoundID)
+
+		if err != nil {
+			log.Panicf("Error Opening Run State: %v", err)
+		}
+
+		defer runState.Close()
+
+		runReport.RunID = runInfo.ID
+		log.Printf("Run ID: %s (continue an unfinished run with -resume %s)", runInfo.ID, runInfo.ID)
+
+		ballotBackup.Start(options.backupDir, action.name, global.Const.RoundID)
+	}
+
+	summary := processAccounts(ctx, threads, action, runReport)
+
+	if err = ballotBackup.Close(); err != nil {
+		log.Errorf("Error Closing Ballot Backup: %v", err)
+	}
+
+	if backupPath := ballotBackup.Path(); backupPath != "" {
+		log.Printf("Ballots Saved To %s (undo with: restore -backup %s)", backupPath, backupPath)
+	}
+
+	jsonPath, csvPath, err := runReport.Write(options.reportDir)
+	if err != nil {
+		log.Errorf("Error Writing Run Report: %v", err)
+	} else {
+		log.Printf("Run Report Saved To %s / %s", jsonPath, csvPath)
+	}
+
+	log.Printf("Accounts: %d | Succeeded: %d | Skipped: %d | Failed: %d | Interrupted: %d | Not Started: %d",
+		summary.total, summary.succeeded, summary.skipped, summary.failed, summary.interrupted, summary.notStarted)
+
+	if options.insecureTLS {
+		log.Warnf("!!! This Run Was Made Without TLS Certificate Verification !!!")
+	}
+
+	if ctx.Err() != nil {
+		log.Warnf("The Work Has Been Stopped")
+	} else {
+		log.Printf("The Work Has Been Successfully Finished")
+	}
+
+	if interactive {
+		inputUser("\nPress Enter to Exit..")
+	}
+
+	if summary.failed > 0 || summary.interrupted > 0 || summary.notStarted > 0 {
+		return 1
+	}
 
-	log.Printf("The Work Has Been Successfully Finished")
-	inputUser("\nPress Enter to Exit..")
+	return 0
 }
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"main/internal/projectFilter"
+	"main/internal/retroActions"
+	util2 "main/internal/util"
+	"main/pkg/types"
+	"main/pkg/util"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type catalogOptions struct {
+	categories        string
+	excludeCategories string
+	statuses          string
+	rankRange         string
+	votersRange       string
+	votesRange        string
+	match             string
+	exclude           string
+	sortBy            string
+	format            string
+	outPath           string
+}
+
+// parseBounds accepts "10-50", "10-" (at least), "-50" (at most) or "10" (exactly), 0 means no bound
+func parseBounds(value string) (int64, int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, 0, nil
+	}
+
+	first, last, isRange := strings.Cut(value, "-")
+	if !isRange {
+		last = first
+	}
+
+	var bounds [2]int64
+
+	for i, part := range []string{first, last} {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bound, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || bound < 0 {
+			return 0, 0, fmt.Errorf("invalid range: %s", value)
+		}
+
+		bounds[i] = bound
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func (o catalogOptions) filter() (types.ProjectFilter, error) {
+	filter := types.ProjectFilter{
+		Categories:        splitList(o.categories),
+		ExcludeCategories: splitList(o.excludeCategories),
+		Statuses:          splitList(o.statuses),
+		Allow:             splitList(o.match),
+		Deny:              splitList(o.exclude),
+	}
+
+	minRank, maxRank, err := parseBounds(o.rankRange)
+	if err != nil {
+		return filter, fmt.Errorf("-rank: %v", err)
+	}
+
+	minVoters, maxVoters, err := parseBounds(o.votersRange)
+	if err != nil {
+		return filter, fmt.Errorf("-voters: %v", err)
+	}
+
+	filter.MinTotalVotes, filter.MaxTotalVotes, err = parseBounds(o.votesRange)
+	if err != nil {
+		return filter, fmt.Errorf("-votes: %v", err)
+	}
+
+	filter.MinRank, filter.MaxRank = int(minRank), int(maxRank)
+	filter.MinUniqueVoters, filter.MaxUniqueVoters = int(minVoters), int(maxVoters)
+
+	return filter, nil
+}
+
+// catalogClient signs in with the first account the voting commands would load from the accounts file,
+// -keystore or -signer, only that account is derived or decrypted. Without accounts the submissions
+// are requested anonymously
+func catalogClient(ctx context.Context, options cliOptions) *retroActions.Client {
+	var accountData types.AccountData
+
+	accountsList, err := loadAccounts(options, 1)
+
+	switch {
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		log.Warnf("Fetching Projects Without Authorization: %v", err)
+	case len(accountsList) > 0:
+		accountData = accountsList[0]
+	}
+
+	client := retroActions.NewClient(util2.GetClient(util.ProxiesCycler.Next()), accountData)
+
+	if accountData.Signer == nil {
+		return client
+	}
+
+	if err := client.Authorize(ctx); err != nil {
+		log.Warnf("Fetching Projects Without Authorization: %v", err)
+		return retroActions.NewClient(util2.GetClient(util.ProxiesCycler.Next()), types.AccountData{})
+	}
+
+	return client
+}
+
+func runProjects(options cliOptions) error {
+	filterSettings, err := options.catalog.filter()
+	if err != nil {
+		return err
+	}
+
+	filter, err := projectFilter.New(filterSettings)
+	if err != nil {
+		return err
+	}
+
+	if err = projectFilter.Sort(nil, options.catalog.sortBy); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	client := catalogClient(ctx, options)
+
+	if externalSigner != nil {
+		defer externalSigner.Close()
+	}
+
+	projectsList, err := client.GetProjectsList(ctx)
+	if err != nil {
+		return err
+	}
+
+	projectsList = filter.Apply(projectsList)
+
+	if err = projectFilter.Sort(projectsList, options.catalog.sortBy); err != nil {
+		return err
+	}
+
+	output := io.Writer(os.Stdout)
+
+	if options.catalog.outPath != "" {
+		file, err := os.Create(options.catalog.outPath)
+		if err != nil {
+			return fmt.Errorf("error when creating %s: %v", options.catalog.outPath, err)
+		}
+		defer file.Close()
+
+		output = file
+	}
+
+	switch strings.ToLower(options.catalog.format) {
+	case "table":
+		err = writeProjectsTable(output, projectsList)
+	case "json":
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(projectsList)
+	case "csv":
+		err = writeProjectsCSV(output, projectsList)
+	default:
+		return fmt.Errorf("unknown format %q, use table, json or csv", options.catalog.format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error when writing projects: %v", err)
+	}
+
+	if options.catalog.outPath != "" {
+		log.Printf("Saved %d Projects To %s", len(projectsList), options.catalog.outPath)
+	}
+
+	return nil
+}
+
+func projectRow(project retroActions.ProjectData) []string {
+	return []string{
+		project.ID,
+		project.Name,
+		project.Status,
+		strconv.Itoa(project.ProjectRank),
+		strconv.Itoa(project.UniqueVoters),
+		strconv.FormatInt(project.TotalVotes, 10),
+		strings.Join(project.Categories, ";"),
+		project.WebsiteURL,
+	}
+}
+
+var projectColumns = []string{"id", "name", "status", "rank", "unique_voters", "total_votes", "categories", "website_url"}
+
+func writeProjectsTable(output io.Writer, projectsList []retroActions.ProjectData) error {
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, strings.ToUpper(strings.Join(projectColumns, "\t")))
+
+	for _, project := range projectsList {
+		fmt.Fprintln(writer, strings.Join(projectRow(project), "\t"))
+	}
+
+	fmt.Fprintf(writer, "\nTotal: %d\n", len(projectsList))
+
+	return writer.Flush()
+}
+
+func writeProjectsCSV(output io.Writer, projectsList []retroActions.ProjectData) error {
+	writer := csv.NewWriter(output)
+
+	if err := writer.Write(projectColumns); err != nil {
+		return err
+	}
+
+	for _, project := range projectsList {
+		if err := writer.Write(projectRow(project)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"main/pkg/global"
+	"main/pkg/util"
+	"path/filepath"
+)
+
+func runVault(options cliOptions) error {
+	keystoreDir := options.keystorePath
+	if keystoreDir == "" {
+		keystoreDir = filepath.Join(options.configDir, "keystore")
+	}
+
+	accountsListString, err := util.ReadFileByRows(options.accountsPath)
+
+	if err != nil {
+		return fmt.Errorf("error reading accounts list file: %v", err)
+	}
+
+	accountsList, err := util.GetAccounts(accountsListString, global.Settings.Derivation, 0)
+
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := util.ReadPassphrase(options.passwordFile, "New Keystore Passphrase: ", true)
+
+	if err != nil {
+		return err
+	}
+
+	if passphrase == "" {
+		return fmt.Errorf("empty keystore passphrase")
+	}
+
+	created, err := util.CreateKeystore(keystoreDir, passphrase, accountsList)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Encrypted %d Of %d Accounts Into %s", created, len(accountsList), keystoreDir)
+	log.Warnf("Verify The Keystore With -keystore %s Before Deleting %s", keystoreDir, options.accountsPath)
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// Command retroMock serves the in-process fake of the Retro9000 API on a local port, so the app can be
+// tried end to end without the real API: set api_base_url in settings.json to the printed address.
+//
+//	go run ./cmd/retroMock -addr 127.0.0.1:9000 -projects 30 -per-page 10
+package main
+
+import (
+	"flag"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"main/internal/retroActions"
+	"main/internal/retroMock"
+	"main/pkg/types"
+	"main/pkg/util"
+	"net/http"
+	"path/filepath"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9000", "address to listen on")
+	configDir := flag.String("config", "config", "config directory, the round ID is read from its const.json")
+	roundID := flag.String("round", "", "round ID (overrides const.json)")
+	projectsCount := flag.Int("projects", 20, "number of approved projects in the round")
+	eligibleVotes := flag.Int64("eligible-votes", 100, "votes every account may cast")
+	perPage := flag.Int("per-page", 0, "caps perPage of the submissions list, 0 - no cap")
+	flag.Parse()
+
+	if *roundID == "" {
+		var constData types.ConstStruct
+
+		if err := util.ReadJsonFile(filepath.Join(*configDir, "const.json"), &constData); err != nil {
+			log.Fatalf("Error Reading const.json (or pass -round): %v", err)
+		}
+
+		*roundID = constData.RoundID
+	}
+
+	projects := make([]retroActions.ProjectData, 0, *projectsCount)
+
+	for i := 1; i <= *projectsCount; i++ {
+		projects = append(projects, retroActions.ProjectData{
+			ID:          fmt.Sprintf("mock-project-%d", i),
+			RoundID:     *roundID,
+			Name:        fmt.Sprintf("Mock Project %d", i),
+			Status:      "approved",
+			Categories:  []string{"Mock"},
+			ProjectRank: i,
+		})
+	}
+
+	server := retroMock.NewHandler(*roundID, projects)
+	server.DefaultEligibleVotes = *eligibleVotes
+	server.MaxPerPage = *perPage
+
+	log.Printf("Mock Retro9000 API For Round %s Listening On http://%s (set api_base_url to it)", *roundID, *addr)
+
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
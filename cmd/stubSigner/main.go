@@ -0,0 +1,112 @@
+// Command stubSigner is a minimal external signer for trying -signer locally: it holds the keys
+// of an accounts file in memory and answers eth_accounts and personal_sign over stdin/stdout.
+//
+//	app vote -signer "stubSigner ./keys.txt" -accounts /dev/null
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	log "github.com/sirupsen/logrus"
+	"main/pkg/global"
+	"main/pkg/signer"
+	"main/pkg/util"
+	"os"
+)
+
+type request struct {
+	ID     int               `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type response struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+
+	if len(os.Args) != 2 {
+		log.Fatalf("Usage: %s <accounts file>", os.Args[0])
+	}
+
+	accountsListString, err := util.ReadFileByRows(os.Args[1])
+	if err != nil {
+		log.Fatalf("Error Reading Accounts File: %v", err)
+	}
+
+	accountsList, err := util.GetAccounts(accountsListString, global.Settings.Derivation, 0)
+	if err != nil {
+		log.Fatalf("Error Loading Accounts: %v", err)
+	}
+
+	signers := map[common.Address]signer.Signer{}
+	addresses := make([]string, 0, len(accountsList))
+
+	for _, accountData := range accountsList {
+		signers[accountData.AccountAddress] = accountData.Signer
+		addresses = append(addresses, accountData.AccountAddress.String())
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+
+	for scanner.Scan() {
+		var req request
+		if err = json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			continue
+		}
+
+		resp := response{JSONRPC: "2.0", ID: req.ID}
+
+		switch req.Method {
+		case "eth_accounts":
+			resp.Result = addresses
+		case "personal_sign":
+			resp.Result, resp.Error = personalSign(signers, req.Params)
+		default:
+			resp.Error = &rpcError{Code: -32601, Message: "method not found"}
+		}
+
+		if err = encoder.Encode(resp); err != nil {
+			log.Fatalf("Error Writing Response: %v", err)
+		}
+	}
+}
+
+func personalSign(signers map[common.Address]signer.Signer, params []json.RawMessage) (interface{}, *rpcError) {
+	var messageHex, address string
+
+	if len(params) != 2 || json.Unmarshal(params[0], &messageHex) != nil || json.Unmarshal(params[1], &address) != nil {
+		return nil, &rpcError{Code: -32602, Message: "expected [message, address]"}
+	}
+
+	message, err := hexutil.Decode(messageHex)
+	if err != nil {
+		return nil, &rpcError{Code: -32602, Message: fmt.Sprintf("invalid message: %v", err)}
+	}
+
+	accountSigner, ok := signers[common.HexToAddress(address)]
+	if !ok {
+		return nil, &rpcError{Code: -32000, Message: "unknown account " + address}
+	}
+
+	signature, err := accountSigner.SignPersonalMessage(message)
+	if err != nil {
+		return nil, &rpcError{Code: -32000, Message: err.Error()}
+	}
+
+	return hexutil.Encode(signature), nil
+}
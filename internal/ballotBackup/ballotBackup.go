@@ -0,0 +1,278 @@
+package ballotBackup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"main/internal/retroActions"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FormatVersion is written to every backup, files of a newer version are refused instead of being misread.
+// Version 1 is a single JSON document, version 2 is a header line followed by one ballot line per account
+const FormatVersion = 2
+
+type Vote struct {
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	Votes       int64  `json:"votes"`
+	Confirmed   bool   `json:"confirmed"`
+}
+
+type AccountBallot struct {
+	Address       string    `json:"address"`
+	EligibleVotes int64     `json:"eligible_votes"`
+	Votes         []Vote    `json:"votes"`
+	SavedAt       time.Time `json:"saved_at"`
+}
+
+type Backup struct {
+	Version   int             `json:"version"`
+	Mode      string          `json:"mode"`
+	RoundID   string          `json:"round_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Accounts  []AccountBallot `json:"accounts,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	header  *Backup
+	saved   map[string]bool
+	file    *os.File // opened with the first ballot
+	fileErr error    // a failed write may leave a partial line, nothing is appended after it
+	dir     string
+)
+
+// Start begins collecting the ballots of a run into backupDir, Add is a no-op until it is called
+func Start(backupDir string, mode string, roundID string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	header = &Backup{
+		Version:   FormatVersion,
+		Mode:      mode,
+		RoundID:   roundID,
+		CreatedAt: time.Now(),
+	}
+	saved = map[string]bool{}
+	file = nil
+	fileErr = nil
+	dir = backupDir
+}
+
+// Add stores the ballot of the account as it was read, a later ballot of the same account is ignored
+// so the backup always holds the state before the run changed anything. The ballot is appended as one
+// line and synced before Add returns, the caller must not change the ballot when it fails
+func Add(address string, votesData *retroActions.GetVotesResponse) error {
+	accountBallot := AccountBallot{
+		Address:       address,
+		EligibleVotes: votesData.Data.TotalEligibleVotes,
+		Votes:         make([]Vote, 0, len(votesData.Data.Votes)),
+		SavedAt:       time.Now(),
+	}
+
+	for _, ballotVote := range votesData.Data.Votes {
+		accountBallot.Votes = append(accountBallot.Votes, Vote{
+			ProjectID:   ballotVote.Project.Id,
+			ProjectName: ballotVote.Project.Name,
+			Votes:       ballotVote.VoteCount,
+			Confirmed:   ballotVote.IsConfirmed,
+		})
+	}
+
+	ballotBytes, err := json.Marshal(accountBallot)
+	if err != nil {
+		return fmt.Errorf("%s | Error When Encoding Ballot Backup: %v", address, err)
+	}
+
+	backupFile, err := appendLine(address, append(ballotBytes, '\n'))
+	if err != nil || backupFile == nil {
+		return err
+	}
+
+	// the line is already written, syncing outside the lock lets other accounts append meanwhile
+	if err = backupFile.Sync(); err != nil {
+		return fmt.Errorf("%s | Error When Saving Ballot Backup: %v", address, err)
+	}
+
+	return nil
+}
+
+// appendLine writes the ballot line once per account and returns the file to sync, or nil
+// when there is nothing to write
+func appendLine(address string, line []byte) (*os.File, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	addressKey := strings.ToLower(address)
+
+	if header == nil || saved[addressKey] {
+		return nil, nil
+	}
+
+	if fileErr != nil {
+		return nil, fmt.Errorf("%s | Error When Saving Ballot Backup: %v", address, fileErr)
+	}
+
+	if file == nil {
+		if err := create(); err != nil {
+			return nil, fmt.Errorf("%s | Error When Saving Ballot Backup: %v", address, err)
+		}
+	}
+
+	if _, err := file.Write(line); err != nil {
+		fileErr = err
+		return nil, fmt.Errorf("%s | Error When Saving Ballot Backup: %v", address, err)
+	}
+
+	saved[addressKey] = true
+
+	return file, nil
+}
+
+// create opens <dir>/ballots_<mode>_<timestamp>.jsonl and makes its header line durable
+func create() error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error when creating backup directory: %v", err)
+	}
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("error when encoding backup: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, fmt.Sprintf("ballots_%s_%s.jsonl", header.Mode, header.CreatedAt.Format("20060102_150405")))
+
+	backupFile, err := os.OpenFile(backupPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = backupFile.Write(append(headerBytes, '\n')); err == nil {
+		err = backupFile.Sync()
+	}
+
+	if err == nil {
+		err = syncDir(dir)
+	}
+
+	if err != nil {
+		_ = backupFile.Close()
+		_ = os.Remove(backupPath)
+		return err
+	}
+
+	file = backupFile
+
+	return nil
+}
+
+// Path returns the backup file of the run, or an empty path when no ballot was added
+func Path() string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file == nil {
+		return ""
+	}
+
+	return file.Name()
+}
+
+// Close closes the backup file, later ballots are not saved
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	header = nil
+
+	if file == nil {
+		return nil
+	}
+
+	return file.Close()
+}
+
+// syncDir makes the new file durable, not every platform can sync a directory
+func syncDir(dirPath string) error {
+	dirFile, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer dirFile.Close()
+
+	if err = dirFile.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+
+	return nil
+}
+
+// Load reads a backup of any known version. A ballot line cut off by a crash is dropped: its Add
+// never returned, so that ballot was not changed
+func Load(path string) (*Backup, error) {
+	backupFile, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error when reading backup: %v", err)
+	}
+	defer backupFile.Close()
+
+	decoder := json.NewDecoder(backupFile)
+
+	var backup Backup
+
+	if err = decoder.Decode(&backup); err != nil {
+		return nil, fmt.Errorf("error when decoding backup %s: %v", path, err)
+	}
+
+	switch {
+	case backup.Version == 0:
+		return nil, fmt.Errorf("%s is not a ballot backup", path)
+	case backup.Version > FormatVersion:
+		return nil, fmt.Errorf("backup %s has version %d, this build reads up to version %d",
+			path, backup.Version, FormatVersion)
+	}
+
+	for backup.Version > 1 {
+		var accountBallot AccountBallot
+
+		err = decoder.Decode(&accountBallot)
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error when decoding backup %s: %v", path, err)
+		}
+
+		backup.Accounts = append(backup.Accounts, accountBallot)
+	}
+
+	for _, accountBallot := range backup.Accounts {
+		for _, vote := range accountBallot.Votes {
+			if vote.ProjectID == "" || vote.Votes <= 0 {
+				return nil, fmt.Errorf("backup %s: %s has an invalid vote for project %q", path, accountBallot.Address, vote.ProjectID)
+			}
+		}
+	}
+
+	return &backup, nil
+}
+
+// ForAccount returns the saved ballot of the address, or nil when the backup has none
+func (b *Backup) ForAccount(address string) *AccountBallot {
+	for i := range b.Accounts {
+		if strings.EqualFold(b.Accounts[i].Address, address) {
+			return &b.Accounts[i]
+		}
+	}
+
+	return nil
+}
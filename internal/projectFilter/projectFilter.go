@@ -0,0 +1,188 @@
+package projectFilter
+
+import (
+	"fmt"
+	"main/internal/retroActions"
+	"main/pkg/global"
+	"main/pkg/types"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type projectMatcher struct {
+	projectID string
+	nameRegex *regexp.Regexp
+}
+
+// Filter is a compiled types.ProjectFilter, deleted projects never pass it
+type Filter struct {
+	settings types.ProjectFilter
+	allow    []projectMatcher
+	deny     []projectMatcher
+}
+
+func compileMatchers(patterns []string) ([]projectMatcher, error) {
+	matchers := make([]projectMatcher, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		nameRegex, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project pattern %q: %v", pattern, err)
+		}
+
+		matchers = append(matchers, projectMatcher{projectID: pattern, nameRegex: nameRegex})
+	}
+
+	return matchers, nil
+}
+
+// DefaultStatus is the only status voted for when project_filter.statuses is empty
+const DefaultStatus = "approved"
+
+// AnyStatus in the statuses list lets projects of every status pass
+const AnyStatus = "*"
+
+// FromSettings compiles the project_filter block of settings.json, an empty statuses list
+// only lets approved projects receive votes
+func FromSettings() (*Filter, error) {
+	settings := global.Settings.ProjectFilter
+
+	if len(settings.Statuses) == 0 {
+		settings.Statuses = []string{DefaultStatus}
+	}
+
+	filter, err := New(settings)
+	if err != nil {
+		return nil, fmt.Errorf("project_filter: %v", err)
+	}
+
+	return filter, nil
+}
+
+func New(settings types.ProjectFilter) (*Filter, error) {
+	allow, err := compileMatchers(settings.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	deny, err := compileMatchers(settings.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{settings: settings, allow: allow, deny: deny}, nil
+}
+
+func matchesAny(matchers []projectMatcher, project retroActions.ProjectData) bool {
+	for _, matcher := range matchers {
+		if matcher.projectID == project.ID || matcher.nameRegex.MatchString(project.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasCategory(project retroActions.ProjectData, categories []string) bool {
+	for _, category := range categories {
+		for _, projectCategory := range project.Categories {
+			if strings.EqualFold(strings.TrimSpace(category), projectCategory) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (f *Filter) Match(project retroActions.ProjectData) bool {
+	settings := f.settings
+
+	switch {
+	case project.IsDeleted:
+		return false
+	case len(settings.Categories) > 0 && !hasCategory(project, settings.Categories):
+		return false
+	case hasCategory(project, settings.ExcludeCategories):
+		return false
+	case settings.MinRank > 0 && project.ProjectRank < settings.MinRank:
+		return false
+	case settings.MaxRank > 0 && project.ProjectRank > settings.MaxRank:
+		return false
+	case settings.MinUniqueVoters > 0 && project.UniqueVoters < settings.MinUniqueVoters:
+		return false
+	case settings.MaxUniqueVoters > 0 && project.UniqueVoters > settings.MaxUniqueVoters:
+		return false
+	case settings.MinTotalVotes > 0 && project.TotalVotes < settings.MinTotalVotes:
+		return false
+	case settings.MaxTotalVotes > 0 && project.TotalVotes > settings.MaxTotalVotes:
+		return false
+	case len(f.allow) > 0 && !matchesAny(f.allow, project):
+		return false
+	case matchesAny(f.deny, project):
+		return false
+	}
+
+	if len(settings.Statuses) > 0 {
+		for _, status := range settings.Statuses {
+			status = strings.TrimSpace(status)
+
+			if status == AnyStatus || strings.EqualFold(status, project.Status) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Apply returns the matching projects in a new slice, the input is left untouched
+func (f *Filter) Apply(projects []retroActions.ProjectData) []retroActions.ProjectData {
+	filtered := make([]retroActions.ProjectData, 0, len(projects))
+
+	for _, project := range projects {
+		if f.Match(project) {
+			filtered = append(filtered, project)
+		}
+	}
+
+	return filtered
+}
+
+var sortKeys = map[string]func(a, b retroActions.ProjectData) bool{
+	"rank":   func(a, b retroActions.ProjectData) bool { return a.ProjectRank < b.ProjectRank },
+	"name":   func(a, b retroActions.ProjectData) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+	"status": func(a, b retroActions.ProjectData) bool { return a.Status < b.Status },
+	"voters": func(a, b retroActions.ProjectData) bool { return a.UniqueVoters < b.UniqueVoters },
+	"votes":  func(a, b retroActions.ProjectData) bool { return a.TotalVotes < b.TotalVotes },
+}
+
+// Sort orders projects in place by rank, name, status, voters or votes; a "-" prefix reverses the order
+func Sort(projects []retroActions.ProjectData, key string) error {
+	key = strings.ToLower(strings.TrimSpace(key))
+	descending := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	less, ok := sortKeys[key]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q, use rank, name, status, voters or votes", key)
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool {
+		if descending {
+			return less(projects[j], projects[i])
+		}
+
+		return less(projects[i], projects[j])
+	})
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"main/internal/retroActions"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StatusSucceeded   = "succeeded"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+	StatusNotStarted  = "not_started"
+	StatusSkipped     = "skipped"
+
+	ConfirmationNone      = "none"
+	ConfirmationPending   = "pending"
+	ConfirmationConfirmed = "confirmed"
+
+	PlannedVote    = "vote"
+	PlannedDelete  = "delete"
+	PlannedConfirm = "confirm"
+)
+
+type ProjectVotes struct {
+	ProjectID string `json:"project_id"`
+	Votes     int64  `json:"votes"`
+}
+
+// PlannedRequest is a mutating request computed in dry run instead of being sent
+type PlannedRequest struct {
+	Action    string   `json:"action"`
+	ProjectID string   `json:"project_id,omitempty"`
+	Votes     int64    `json:"votes,omitempty"`
+	VoteIDs   []string `json:"vote_ids,omitempty"`
+}
+
+type AccountReport struct {
+	Address       string           `json:"address"`
+	Status        string           `json:"status"`
+	EligibleVotes int64            `json:"eligible_votes"`
+	UsedVotes     int64            `json:"used_votes"`
+	VotesCast     []ProjectVotes   `json:"votes_cast"`
+	VotesDeleted  []ProjectVotes   `json:"votes_deleted"`
+	Planned       []PlannedRequest `json:"planned_requests,omitempty"`
+	Confirmation  string           `json:"confirmation"`
+	PendingVotes  int              `json:"pending_votes"`
+	Error         string           `json:"error"`
+	StartedAt     *time.Time       `json:"started_at"`
+	FinishedAt    *time.Time       `json:"finished_at"`
+	DurationMs    int64            `json:"duration_ms"`
+}
+
+type Report struct {
+	Mode       string          `json:"mode"`
+	RunID      string          `json:"run_id,omitempty"`
+	DryRun     bool            `json:"dry_run"`
+	RoundID    string          `json:"round_id"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Accounts   []AccountReport `json:"accounts"`
+}
+
+func New(
+	mode string,
+	roundID string,
+	dryRun bool,
+	addresses []string,
+) *Report {
+	accounts := make([]AccountReport, len(addresses))
+
+	for i, address := range addresses {
+		accounts[i] = AccountReport{
+			Address:      address,
+			Status:       StatusNotStarted,
+			Confirmation: ConfirmationNone,
+		}
+	}
+
+	return &Report{
+		Mode:      mode,
+		DryRun:    dryRun,
+		RoundID:   roundID,
+		StartedAt: time.Now(),
+		Accounts:  accounts,
+	}
+}
+
+func (r *AccountReport) Start() {
+	now := time.Now()
+	r.StartedAt = &now
+}
+
+func (r *AccountReport) Finish(status string, err error) {
+	now := time.Now()
+	r.FinishedAt = &now
+	r.Status = status
+
+	if r.StartedAt != nil {
+		r.DurationMs = now.Sub(*r.StartedAt).Milliseconds()
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+func (r *AccountReport) SetBallot(votesData *retroActions.GetVotesResponse) {
+	pendingVotes := len(votesData.NotConfirmedIDs())
+
+	r.EligibleVotes = votesData.Data.TotalEligibleVotes
+	r.UsedVotes = votesData.Data.UsedVotes
+	r.PendingVotes = pendingVotes
+
+	switch {
+	case len(votesData.Data.Votes) == 0:
+		r.Confirmation = ConfirmationNone
+	case pendingVotes > 0:
+		r.Confirmation = ConfirmationPending
+	default:
+		r.Confirmation = ConfirmationConfirmed
+	}
+}
+
+func (r *AccountReport) MarkConfirmed() {
+	r.PendingVotes = 0
+
+	if r.Confirmation != ConfirmationNone || len(r.VotesCast) > 0 {
+		r.Confirmation = ConfirmationConfirmed
+	}
+}
+
+func (r *AccountReport) AddVoteCast(projectID string, votes int64) {
+	r.VotesCast = append(r.VotesCast, ProjectVotes{ProjectID: projectID, Votes: votes})
+}
+
+func (r *AccountReport) AddVoteDeleted(projectID string, votes int64) {
+	r.VotesDeleted = append(r.VotesDeleted, ProjectVotes{ProjectID: projectID, Votes: votes})
+}
+
+func (r *AccountReport) AddPlanned(request PlannedRequest) {
+	r.Planned = append(r.Planned, request)
+}
+
+// Write stores the report as <dir>/<mode>_<timestamp>.json and .csv and returns both paths
+func (r *Report) Write(dir string) (string, string, error) {
+	r.FinishedAt = time.Now()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("error when creating report directory: %v", err)
+	}
+
+	mode := r.Mode
+	if r.DryRun {
+		mode += "_dry_run"
+	}
+
+	baseName := filepath.Join(dir, fmt.Sprintf("%s_%s", mode, r.StartedAt.Format("20060102_150405")))
+	jsonPath := baseName + ".json"
+	csvPath := baseName + ".csv"
+
+	jsonBytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", "", fmt.Errorf("error when encoding report: %v", err)
+	}
+
+	if err = os.WriteFile(jsonPath, jsonBytes, 0644); err != nil {
+		return "", "", fmt.Errorf("error when writing report: %v", err)
+	}
+
+	if err = r.writeCSV(csvPath); err != nil {
+		return "", "", err
+	}
+
+	return jsonPath, csvPath, nil
+}
+
+func (r *Report) writeCSV(csvPath string) error {
+	file, err := os.OpenFile(csvPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error when writing report: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	rows := [][]string{{
+		"address", "mode", "status", "eligible_votes", "used_votes", "votes_cast", "votes_cast_total",
+		"votes_deleted", "planned_requests", "confirmation", "pending_votes", "error", "started_at", "finished_at", "duration_ms",
+	}}
+
+	for _, account := range r.Accounts {
+		rows = append(rows, []string{
+			account.Address,
+			r.Mode,
+			account.Status,
+			strconv.FormatInt(account.EligibleVotes, 10),
+			strconv.FormatInt(account.UsedVotes, 10),
+			formatProjectVotes(account.VotesCast),
+			strconv.FormatInt(sumProjectVotes(account.VotesCast), 10),
+			formatProjectVotes(account.VotesDeleted),
+			formatPlanned(account.Planned),
+			account.Confirmation,
+			strconv.Itoa(account.PendingVotes),
+			account.Error,
+			formatTime(account.StartedAt),
+			formatTime(account.FinishedAt),
+			strconv.FormatInt(account.DurationMs, 10),
+		})
+	}
+
+	if err = writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("error when writing report: %v", err)
+	}
+
+	return nil
+}
+
+func formatProjectVotes(projectVotes []ProjectVotes) string {
+	parts := make([]string, 0, len(projectVotes))
+
+	for _, data := range projectVotes {
+		parts = append(parts, fmt.Sprintf("%s:%d", data.ProjectID, data.Votes))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func formatPlanned(planned []PlannedRequest) string {
+	parts := make([]string, 0, len(planned))
+
+	for _, request := range planned {
+		switch {
+		case len(request.VoteIDs) > 0:
+			parts = append(parts, fmt.Sprintf("%s:%s", request.Action, strings.Join(request.VoteIDs, ",")))
+		case request.ProjectID != "":
+			parts = append(parts, fmt.Sprintf("%s:%s:%d", request.Action, request.ProjectID, request.Votes))
+		default:
+			parts = append(parts, fmt.Sprintf("%s:%d", request.Action, request.Votes))
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func sumProjectVotes(projectVotes []ProjectVotes) int64 {
+	var total int64
+
+	for _, data := range projectVotes {
+		total += data.Votes
+	}
+
+	return total
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
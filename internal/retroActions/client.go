@@ -0,0 +1,257 @@
+package retroActions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"main/internal/util"
+	"main/pkg/global"
+	"main/pkg/types"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api-retro-9000.avax.network"
+
+type Client struct {
+	httpClient       *fasthttp.Client
+	baseURL          string
+	headers          [][2]string
+	accountData      types.AccountData
+	accessToken      string
+	refreshToken     string
+	accessExpiresAt  time.Time // zero when the API did not tell
+	refreshExpiresAt time.Time
+	sessionKey       []byte // derived once from the account signer, see cacheKey
+	retry            types.RetrySettings
+	dryRun           bool
+}
+
+type apiRequest struct {
+	method  string
+	path    string
+	payload interface{}
+	action  string
+	mutates bool
+	signIn  bool // part of the sign-in, a rejection is not answered by renewing the session
+}
+
+// BaseURL is the API address from the settings, or the public API when it is not set
+func BaseURL() string {
+	if baseURL := strings.TrimRight(global.Settings.APIBaseURL, "/"); baseURL != "" {
+		return baseURL
+	}
+
+	return defaultBaseURL
+}
+
+func NewClient(
+	httpClient *fasthttp.Client,
+	accountData types.AccountData,
+) *Client {
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    BaseURL(),
+		headers: [][2]string{
+			{"accept", "application/json, text/plain, */*"},
+			{"accept-language", "ru,en;q=0.9"},
+			{"origin", "https://retro9000.avax.network"},
+			{"referer", "https://retro9000.avax.network/"},
+		},
+		accountData: accountData,
+		retry:       global.Settings.Retry,
+		dryRun:      global.DryRun,
+	}
+}
+
+func (c *Client) do(
+	ctx context.Context,
+	request apiRequest,
+	responseData apiResponse,
+	validate func(resp *fasthttp.Response) error,
+) error {
+	var payloadBytes []byte
+
+	// flows must not reach here in dry run, this is the last line of defence for the live ballot
+	if request.mutates && c.dryRun {
+		return fmt.Errorf("%s | Refused %s In Dry Run", c.accountData.AccountAddress.String(), request.action)
+	}
+
+	if request.payload != nil {
+		var err error
+
+		payloadBytes, err = json.Marshal(request.payload)
+		if err != nil {
+			return fmt.Errorf("%s | Failed to marshal JSON payload When %s: %s",
+				c.accountData.AccountAddress.String(), request.action, err)
+		}
+	}
+
+	renewed := false
+
+	for attempt := 1; ; attempt++ {
+		err := c.doAttempt(request, payloadBytes, responseData, validate)
+		if err == nil {
+			return nil
+		}
+
+		// the rejected request was not processed, so it is safe to send it again with the new session
+		if !renewed && c.sessionExpired(request, err) {
+			renewed = true
+
+			if err = c.renewSession(ctx, err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		var retryErr *retryableError
+		if !errors.As(err, &retryErr) {
+			return err
+		}
+
+		if c.retry.MaxAttempts > 0 && attempt >= c.retry.MaxAttempts {
+			return &RetriesExhaustedError{
+				Address:  c.accountData.AccountAddress.String(),
+				Action:   request.action,
+				Attempts: attempt,
+				LastErr:  retryErr.err,
+			}
+		}
+
+		if err = c.backoff(ctx, request.action, attempt, retryErr.err); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) backoff(
+	ctx context.Context,
+	action string,
+	attempt int,
+	cause error,
+) error {
+	delay := backoffDelay(c.retry, attempt)
+	log.Printf("%s | Attempt %d When %s Failed: %s, Retrying In %s",
+		c.accountData.AccountAddress.String(), attempt, action, cause, delay)
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%s | Stopped Retrying %s: %w",
+			c.accountData.AccountAddress.String(), action, util.ErrInterrupted)
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+func (c *Client) doAttempt(
+	request apiRequest,
+	payloadBytes []byte,
+	responseData apiResponse,
+	validate func(resp *fasthttp.Response) error,
+) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(c.baseURL + request.path)
+	req.Header.SetMethod(request.method)
+
+	for _, header := range c.headers {
+		req.Header.Set(header[0], header[1])
+	}
+
+	if request.method != fasthttp.MethodGet {
+		req.Header.Set("content-type", "application/json")
+	}
+
+	if c.accessToken != "" || c.refreshToken != "" {
+		req.Header.SetCookie("accessToken", c.accessToken)
+		req.Header.SetCookie("refreshToken", c.refreshToken)
+	}
+
+	if payloadBytes != nil {
+		req.SetBody(payloadBytes)
+	}
+
+	err := c.httpClient.Do(req, resp)
+	if err != nil && util.IsCertificateError(err) {
+		return &FatalError{
+			Address: c.accountData.AccountAddress.String(),
+			Action:  request.action,
+			Kind:    KindUntrustedCertificate,
+			Message: err.Error(),
+			cause:   err,
+		}
+	}
+
+	if err != nil {
+		return &retryableError{fmt.Errorf("request error: %s", err)}
+	}
+
+	if isRetryableStatus(resp.StatusCode()) {
+		return &retryableError{fmt.Errorf("http status %d, response: %s", resp.StatusCode(), string(resp.Body()))}
+	}
+
+	*responseData.status() = responseStatus{}
+
+	if err = json.Unmarshal(resp.Body(), responseData); err != nil {
+		if resp.StatusCode() >= 400 {
+			return c.fatalError(request, resp.StatusCode(), string(resp.Body()))
+		}
+
+		return &retryableError{fmt.Errorf("failed to parse JSON response: %s, response: %s", err, string(resp.Body()))}
+	}
+
+	status := responseData.status()
+	statusCode := status.StatusCode
+	if statusCode == 0 {
+		statusCode = resp.StatusCode()
+	}
+
+	if isRetryableStatus(statusCode) {
+		return &retryableError{fmt.Errorf("status %d, response: %s", statusCode, string(resp.Body()))}
+	}
+
+	if statusCode != 200 {
+		return c.fatalError(request, statusCode, status.Message)
+	}
+
+	if err = validate(resp); err != nil {
+		var retryErr *retryableError
+		if errors.As(err, &retryErr) {
+			return err
+		}
+
+		return &FatalError{
+			Address:    c.accountData.AccountAddress.String(),
+			Action:     request.action,
+			Kind:       KindUnexpectedResponse,
+			StatusCode: statusCode,
+			Message:    fmt.Sprintf("%s, response: %s", err, string(resp.Body())),
+			cause:      err,
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) fatalError(
+	request apiRequest,
+	statusCode int,
+	message string,
+) *FatalError {
+	return &FatalError{
+		Address:    c.accountData.AccountAddress.String(),
+		Action:     request.action,
+		Kind:       classifyStatus(statusCode, message),
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
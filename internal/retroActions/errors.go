@@ -0,0 +1,155 @@
+package retroActions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errNoSessionCookies = errors.New("no session cookies in response")
+
+type FatalErrorKind int
+
+const (
+	KindRejected FatalErrorKind = iota
+	KindAuthRejected
+	KindBallotClosed
+	KindUnexpectedResponse
+	KindSessionExpired
+	KindUntrustedCertificate
+)
+
+func (k FatalErrorKind) String() string {
+	switch k {
+	case KindAuthRejected:
+		return "Auth Rejected"
+	case KindBallotClosed:
+		return "Ballot Closed"
+	case KindUnexpectedResponse:
+		return "Unexpected Response"
+	case KindSessionExpired:
+		return "Session Expired"
+	case KindUntrustedCertificate:
+		return "Untrusted Certificate"
+	default:
+		return "Request Rejected"
+	}
+}
+
+// FatalError is returned when retrying the request cannot change its outcome
+type FatalError struct {
+	Address    string
+	Action     string
+	Kind       FatalErrorKind
+	StatusCode int
+	Message    string
+	cause      error
+}
+
+func (e *FatalError) Error() string {
+	return fmt.Sprintf("%s | %s When %s: status %d, message: %s",
+		e.Address, e.Kind, e.Action, e.StatusCode, e.Message)
+}
+
+func (e *FatalError) Unwrap() error {
+	return e.cause
+}
+
+// AbortsAccount reports whether no further request for this account can succeed
+func (e *FatalError) AbortsAccount() bool {
+	return e.Kind == KindAuthRejected || e.Kind == KindBallotClosed || e.Kind == KindSessionExpired ||
+		e.Kind == KindUntrustedCertificate
+}
+
+// RetriesExhaustedError is returned when every attempt failed with a retryable error
+type RetriesExhaustedError struct {
+	Address  string
+	Action   string
+	Attempts int
+	LastErr  error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("%s | Gave Up %s After %d Attempts: %s",
+		e.Address, e.Action, e.Attempts, e.LastErr)
+}
+
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.LastErr
+}
+
+// BatchError is returned when some requests of a batch failed while the rest of the batch was still sent,
+// the account must not count as done so the failed requests are retried on the next run
+type BatchError struct {
+	Address string
+	Action  string
+	Total   int
+	Errs    []error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%s | %d Of %d Requests Failed When %s, First Error: %s",
+		e.Address, len(e.Errs), e.Total, e.Action, e.Errs[0])
+}
+
+func (e *BatchError) Unwrap() []error {
+	return e.Errs
+}
+
+// Add records a failed request, an error that aborts the account is returned to stop the batch
+func (e *BatchError) Add(err error) error {
+	var fatalErr *FatalError
+	if errors.As(err, &fatalErr) && fatalErr.AbortsAccount() {
+		return err
+	}
+
+	e.Errs = append(e.Errs, err)
+
+	return nil
+}
+
+// Err returns nil when every request of the batch succeeded
+func (e *BatchError) Err() error {
+	if len(e.Errs) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == 429 || statusCode >= 500
+}
+
+func classifyStatus(statusCode int, message string) FatalErrorKind {
+	lowerMessage := strings.ToLower(message)
+
+	if strings.Contains(lowerMessage, "expired") &&
+		(strings.Contains(lowerMessage, "token") || strings.Contains(lowerMessage, "jwt") || strings.Contains(lowerMessage, "session")) {
+		return KindSessionExpired
+	}
+
+	if statusCode == 401 || statusCode == 403 {
+		return KindAuthRejected
+	}
+
+	for _, marker := range []string{"closed", "ended", "not active", "not open"} {
+		if strings.Contains(lowerMessage, marker) {
+			return KindBallotClosed
+		}
+	}
+
+	return KindRejected
+}
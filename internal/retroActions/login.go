@@ -0,0 +1,120 @@
+package retroActions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	log "github.com/sirupsen/logrus"
+	"main/internal/siwe"
+	"main/pkg/global"
+	"main/pkg/signer"
+	"time"
+)
+
+// signInClockSkew tolerates a server clock that is slightly ahead or behind
+const signInClockSkew = time.Minute
+
+func signInPolicy() siwe.Policy {
+	settings := global.Settings.SignIn
+
+	return siwe.Policy{
+		Domains:        settings.Domains,
+		ChainIDs:       settings.ChainIDs,
+		MaxAge:         time.Duration(settings.MaxAgeSeconds) * time.Second,
+		ClockSkew:      signInClockSkew,
+		AllowPlainText: settings.AllowPlainText,
+		// a server that got the cache key message signed could decrypt the session cache
+		Reserved: []string{sessionKeyMessage},
+	}
+}
+
+func (c *Client) Login(
+	ctx context.Context,
+) error {
+	for attempt := 1; ; attempt++ {
+		err := c.signIn(ctx)
+
+		if !errors.Is(err, errNoSessionCookies) {
+			return err
+		}
+
+		if c.retry.MaxAttempts > 0 && attempt >= c.retry.MaxAttempts {
+			return err
+		}
+
+		if err = c.backoff(ctx, "Signing In", attempt, errNoSessionCookies); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) signIn(
+	ctx context.Context,
+) error {
+	signText, err := c.GetSignText(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// the text comes from the network, it is signed only when it is a sign-in for this account
+	err = siwe.Check(signText, c.accountData.AccountAddress, signInPolicy(), time.Now())
+
+	if errors.Is(err, siwe.ErrNotSIWE) {
+		return fmt.Errorf("%s | Refusing To Sign The Sign-In Message: %w "+
+			"(set sign_in.allow_plain_text_message only if the API is known to send plain text)",
+			c.accountData.AccountAddress.String(), err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s | Refusing To Sign The Sign-In Message: %w", c.accountData.AccountAddress.String(), err)
+	}
+
+	if c.accountData.Signer == nil {
+		return fmt.Errorf("%s | No Signer For The Account", c.accountData.AccountAddress.String())
+	}
+
+	signature, err := c.accountData.Signer.SignPersonalMessage([]byte(signText))
+
+	if err != nil {
+		return fmt.Errorf("%s | Failed to sign auth message: %s", c.accountData.AccountAddress.String(), err)
+	}
+
+	if err = signer.Verify(c.accountData.AccountAddress, []byte(signText), signature); err != nil {
+		return fmt.Errorf("%s | Auth message signature does not match the account: %s", c.accountData.AccountAddress.String(), err)
+	}
+
+	return c.DoAuth(ctx, hexutil.Encode(signature))
+}
+
+// sessionExpired reports whether the request was rejected because the access token is no longer accepted
+func (c *Client) sessionExpired(request apiRequest, err error) bool {
+	var fatalErr *FatalError
+
+	if request.signIn || c.accessToken == "" || !errors.As(err, &fatalErr) {
+		return false
+	}
+
+	return fatalErr.Kind == KindSessionExpired || fatalErr.Kind == KindAuthRejected
+}
+
+// renewSession exchanges the refresh token for a new session and signs in again when the refresh fails
+func (c *Client) renewSession(
+	ctx context.Context,
+	cause error,
+) error {
+	log.Printf("%s | Session Rejected (%v), Refreshing", c.accountData.AccountAddress.String(), cause)
+
+	err := c.RefreshSession(ctx)
+
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("%s | Failed To Refresh Session: %v, Signing In Again", c.accountData.AccountAddress.String(), err)
+
+	c.accessToken, c.refreshToken = "", ""
+
+	return c.Login(ctx)
+}
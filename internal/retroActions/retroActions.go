@@ -1,414 +1,278 @@
 package retroActions
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
-	"main/internal/util"
 	"main/pkg/global"
 	"main/pkg/types"
+	"sort"
+	"sync"
 )
 
-func GetSignText(
-	client *fasthttp.Client,
-	accountData types.AccountData,
-) string {
-	url := fmt.Sprintf("https://api-retro-9000.avax.network/api/auth/get-nonce/%s",
-		accountData.AccountAddress.String())
-
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("GET")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-
-		resp := fasthttp.AcquireResponse()
-
-		err := client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error When Making a Request to Retrieve Sign Text %s",
-				accountData.AccountAddress.String(), err)
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		responseData := &getSignTextResponse{}
-
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed to Parse JSON Response While Retrieving Sign Text: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		if responseData.StatusCode != 200 || responseData.Data.Nonce == "" {
-			log.Printf("%s | Failed To Parse Sign Text: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
+const projectsPerPage = 1000
+
+func (c *Client) GetSignText(
+	ctx context.Context,
+) (string, error) {
+	responseData := &getSignTextResponse{}
+
+	err := c.do(ctx, apiRequest{
+		method: fasthttp.MethodGet,
+		path:   fmt.Sprintf("/api/auth/get-nonce/%s", c.accountData.AccountAddress.String()),
+		action: "Retrieving Sign Text",
+		signIn: true,
+	}, responseData, func(resp *fasthttp.Response) error {
+		if responseData.Data.Nonce == "" {
+			return errors.New("empty nonce")
 		}
 
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
+		return nil
+	})
 
-		return responseData.Data.Nonce
+	if err != nil {
+		return "", err
 	}
+
+	return responseData.Data.Nonce, nil
 }
 
-func DoAuth(
-	client *fasthttp.Client,
-	accountData types.AccountData,
+func (c *Client) DoAuth(
+	ctx context.Context,
 	signedMessage string,
-) (string, string, error) {
-	url := "https://api-retro-9000.avax.network/api/auth/login"
-	payload := map[string]string{
-		"walletAddress": accountData.AccountAddress.String(),
-		"signature":     signedMessage,
-	}
+) error {
+	responseData := &doLoginResponse{}
+
+	return c.do(ctx, apiRequest{
+		method: fasthttp.MethodPost,
+		path:   "/api/auth/login",
+		payload: map[string]string{
+			"walletAddress": c.accountData.AccountAddress.String(),
+			"signature":     signedMessage,
+		},
+		action: "Logging In",
+		signIn: true,
+	}, responseData, func(resp *fasthttp.Response) error {
+		// the nonce is spent at this point, so Login restarts the whole sign-in instead of retrying
+		return c.storeSession(resp, true)
+	})
+}
 
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return "", "", fmt.Errorf("%s | Failed to marshal JSON payload when Logging: %s",
-			accountData.AccountAddress.String(), err)
+// RefreshSession exchanges the refresh token for a new access token, the refresh token is
+// replaced only when the response rotates it
+func (c *Client) RefreshSession(
+	ctx context.Context,
+) error {
+	if c.refreshToken == "" {
+		return fmt.Errorf("%s | No Refresh Token", c.accountData.AccountAddress.String())
 	}
 
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("POST")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("content-type", "application/json")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-		req.SetBody(payloadBytes)
-
-		resp := fasthttp.AcquireResponse()
+	responseData := &refreshSessionResponse{}
 
-		err = client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error While Making a Login Request %s",
-				accountData.AccountAddress.String(), err)
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
+	return c.do(ctx, apiRequest{
+		method: fasthttp.MethodPost,
+		path:   "/api/auth/refresh",
+		action: "Refreshing Session",
+		signIn: true,
+	}, responseData, func(resp *fasthttp.Response) error {
+		return c.storeSession(resp, false)
+	})
+}
 
-		responseData := &doLoginResponse{}
+// GetProjectsList follows the pagination metadata and checks that every submission was received.
+// Pages are walked in creation order, which votes cast meanwhile cannot change, and the list is
+// sorted by votes afterwards
+func (c *Client) GetProjectsList(
+	ctx context.Context,
+) ([]ProjectData, error) {
+	var projects []ProjectData
+	var total int
+	seenProjects := map[string]bool{}
+
+	for page := 1; ; {
+		responseData := &getProjectsListResponse{}
 
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response While Logging In: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
+		err := c.do(ctx, apiRequest{
+			method: fasthttp.MethodGet,
+			path: fmt.Sprintf("/api/rounds/%s/submissions?roundId=%s&page=%d&perPage=%d&sortBy=createdAt&sortOrder=asc",
+				global.Const.RoundID, global.Const.RoundID, page, projectsPerPage),
+			action: fmt.Sprintf("Parsing Projects List (Page %d)", page),
+		}, responseData, func(resp *fasthttp.Response) error {
+			return nil
+		})
 
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
+		if err != nil {
+			return nil, err
 		}
 
-		if responseData.StatusCode != 200 {
-			log.Printf("%s | Wrong Response While Logging In: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
+		// a repeated project means the API order moved anyway, the total check below reports a lost one
+		for _, project := range responseData.Data {
+			if !seenProjects[project.ID] {
+				seenProjects[project.ID] = true
+				projects = append(projects, project)
+			}
 		}
 
-		accessTokenCookie := resp.Header.PeekCookie("accessToken")
-		refreshTokenCookie := resp.Header.PeekCookie("refreshToken")
-
-		if accessTokenCookie == nil || refreshTokenCookie == nil {
-			log.Printf("%s | No Cookies In response While Logging In, response: %s", accountData.AccountAddress.String(), string(resp.Body()))
+		metadata := responseData.Metadata
+		total = metadata.Total
 
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
+		if metadata.Next == nil || *metadata.Next <= page || (metadata.LastPage > 0 && *metadata.Next > metadata.LastPage) {
+			break
 		}
 
-		accessTokenCookieString := util.ExtractCookieValue(string(accessTokenCookie),
-			"accessToken")
-		refreshTokenCookieString := util.ExtractCookieValue(string(refreshTokenCookie),
-			"refreshToken")
-
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
-
-		return accessTokenCookieString, refreshTokenCookieString, nil
+		page = *metadata.Next
 	}
-}
 
-func GetProjectsList(
-	client *fasthttp.Client,
-	accountData types.AccountData,
-	accessToken string,
-	refreshToken string,
-) []ProjectData {
-	url := fmt.Sprintf("https://api-retro-9000.avax.network/api/rounds/%s/submissions?roundId=%s&page=1&perPage=1000&sortBy=votes&sortOrder=desc&includeField=userVotes",
-		global.Const.RoundID, global.Const.RoundID)
-
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("GET")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-		req.Header.SetCookie("accessToken", accessToken)
-		req.Header.SetCookie("refreshToken", refreshToken)
-
-		resp := fasthttp.AcquireResponse()
-
-		err := client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error When Parsing Projects List %s",
-				accountData.AccountAddress.String(), err)
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		responseData := &getProjectsListResponse{}
-
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response When Parsing Projects List: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
+	if len(projects) != total {
+		return nil, &FatalError{
+			Address:    c.accountData.AccountAddress.String(),
+			Action:     "Parsing Projects List",
+			Kind:       KindUnexpectedResponse,
+			StatusCode: 200,
+			Message:    fmt.Sprintf("fetched %d projects, but the API reports %d", len(projects), total),
 		}
+	}
 
-		if responseData.StatusCode != 200 {
-			log.Printf("%s | Wrong Response When Parsing Projects List: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].TotalVotes != projects[j].TotalVotes {
+			return projects[i].TotalVotes > projects[j].TotalVotes
 		}
 
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
+		return projects[i].ID < projects[j].ID
+	})
 
-		return responseData.Data
-	}
+	return projects, nil
 }
 
-func DoVote(
-	client *fasthttp.Client,
-	accountData types.AccountData,
-	accessToken string,
-	refreshToken string,
-	projectID string,
-	voteCount int64,
-) error {
-	url := fmt.Sprintf("https://api-retro-9000.avax.network/api/vote/rounds/%s/projects/%s/vote",
-		global.Const.RoundID, projectID)
+var projectsCache struct {
+	sync.Mutex
+	roundID  string
+	projects []ProjectData
+}
 
-	payload := map[string]int64{
-		"voteCount": voteCount,
+// GetSharedProjectsList fetches the projects once per round and shares them between all accounts,
+// concurrent callers wait for the first fetch instead of repeating it. The result must not be modified.
+// The list holds no per-account data, the votes of an account are read from its own ballot
+func (c *Client) GetSharedProjectsList(
+	ctx context.Context,
+) ([]ProjectData, error) {
+	projectsCache.Lock()
+	defer projectsCache.Unlock()
+
+	if projectsCache.projects != nil && projectsCache.roundID == global.Const.RoundID {
+		return projectsCache.projects, nil
 	}
 
-	payloadBytes, err := json.Marshal(payload)
+	projects, err := c.GetProjectsList(ctx)
+
 	if err != nil {
-		return fmt.Errorf("%s | Failed to marshal JSON payload when Logging: %s",
-			accountData.AccountAddress.String(), err)
+		return nil, err
 	}
 
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("POST")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-		req.Header.Set("content-type", "application/json")
-		req.Header.SetCookie("accessToken", accessToken)
-		req.Header.SetCookie("refreshToken", refreshToken)
-		req.SetBody(payloadBytes)
-
-		resp := fasthttp.AcquireResponse()
-
-		err = client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error When Voting: %s",
-				accountData.AccountAddress.String(), err)
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		responseData := &doVoteResponse{}
+	log.Printf("Fetched %d Projects Of Round %s", len(projects), global.Const.RoundID)
 
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response When Voting: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
+	projectsCache.roundID = global.Const.RoundID
+	projectsCache.projects = projects
 
-		if responseData.StatusCode != 200 || responseData.Message != "Voting successful!" {
-			log.Printf("%s | Wrong Response When Voting: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
-
-		return nil
-	}
+	return projects, nil
 }
 
-func getBollotsRequest(
-	client *fasthttp.Client,
+// FetchSharedProjectsList signs in with the account and fills the shared projects list,
+// so a plan can be checked against it before any account starts
+func FetchSharedProjectsList(
+	ctx context.Context,
+	httpClient *fasthttp.Client,
 	accountData types.AccountData,
-	accessToken string,
-	refreshToken string,
-) {
-	url := fmt.Sprintf("https://api-retro-9000.avax.network/api/vote/rounds/%s/ballot",
-		global.Const.RoundID)
-
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("GET")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-		req.Header.SetCookie("accessToken", accessToken)
-		req.Header.SetCookie("refreshToken", refreshToken)
-
-		resp := fasthttp.AcquireResponse()
-
-		err := client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error When Sending Ballot Request %s",
-				accountData.AccountAddress.String(), err)
+) ([]ProjectData, error) {
+	client := NewClient(httpClient, accountData)
 
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		responseData := &GetBallotsResponse{}
-
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response When Sending Ballot Request: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		if responseData == nil || responseData.StatusCode != 200 {
-			log.Printf("%s | Wrong Response When Sending Ballot Request: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
-
-		return
+	if err := client.Authorize(ctx); err != nil {
+		return nil, err
 	}
+
+	return client.GetSharedProjectsList(ctx)
 }
 
-func GetVotes(
-	client *fasthttp.Client,
+// FetchVotes signs in with the account and reads its ballot
+func FetchVotes(
+	ctx context.Context,
+	httpClient *fasthttp.Client,
 	accountData types.AccountData,
-	accessToken string,
-	refreshToken string,
-) *GetVotesResponse {
-	getBollotsRequest(client, accountData, accessToken, refreshToken)
-
-	url := fmt.Sprintf("https://api-retro-9000.avax.network/api/vote/rounds/%s/ballot-votes",
-		global.Const.RoundID)
-
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("GET")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-		req.Header.SetCookie("accessToken", accessToken)
-		req.Header.SetCookie("refreshToken", refreshToken)
-
-		resp := fasthttp.AcquireResponse()
-
-		err := client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error When Parsing Votes %s",
-				accountData.AccountAddress.String(), err)
+) (*GetVotesResponse, error) {
+	client := NewClient(httpClient, accountData)
 
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
+	if err := client.Authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	return client.GetVotes(ctx)
+}
 
-		responseData := &GetVotesResponse{}
+func (c *Client) DoVote(
+	ctx context.Context,
+	projectID string,
+	voteCount int64,
+) error {
+	responseData := &doVoteResponse{}
+
+	return c.do(ctx, apiRequest{
+		method: fasthttp.MethodPost,
+		path:   fmt.Sprintf("/api/vote/rounds/%s/projects/%s/vote", global.Const.RoundID, projectID),
+		payload: map[string]int64{
+			"voteCount": voteCount,
+		},
+		action:  "Voting",
+		mutates: true,
+	}, responseData, expectMessage(&responseData.responseStatus, "Voting successful!"))
+}
 
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response When Parsing Votes: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
+func (c *Client) getBallots(
+	ctx context.Context,
+) error {
+	responseData := &GetBallotsResponse{}
 
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
+	return c.do(ctx, apiRequest{
+		method: fasthttp.MethodGet,
+		path:   fmt.Sprintf("/api/vote/rounds/%s/ballot", global.Const.RoundID),
+		action: "Sending Ballot Request",
+	}, responseData, func(resp *fasthttp.Response) error {
+		return nil
+	})
+}
 
-		if responseData.StatusCode == 404 && responseData.Message == "Ballot not found!" {
-			return responseData
-		}
+func (c *Client) GetVotes(
+	ctx context.Context,
+) (*GetVotesResponse, error) {
+	if err := c.getBallots(ctx); err != nil {
+		return nil, err
+	}
 
-		if responseData == nil || responseData.StatusCode != 200 {
-			log.Printf("%s | Wrong Response When Parsing Votes: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
+	responseData := &GetVotesResponse{}
 
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
+	err := c.do(ctx, apiRequest{
+		method: fasthttp.MethodGet,
+		path:   fmt.Sprintf("/api/vote/rounds/%s/ballot-votes", global.Const.RoundID),
+		action: "Parsing Votes",
+	}, responseData, func(resp *fasthttp.Response) error {
+		return nil
+	})
 
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
+	var fatalErr *FatalError
+	if errors.As(err, &fatalErr) && fatalErr.StatusCode == 404 && fatalErr.Message == "Ballot not found!" {
+		return responseData, nil
+	}
 
-		return responseData
+	if err != nil {
+		return nil, err
 	}
+
+	return responseData, nil
 }
 
-func ApproveVotes(
-	client *fasthttp.Client,
-	accountData types.AccountData,
-	accessToken string,
-	refreshToken string,
+func (c *Client) ApproveVotes(
+	ctx context.Context,
 	votesIDs []string,
 ) error {
 	payload := map[string][]map[string]string{
@@ -419,124 +283,40 @@ func ApproveVotes(
 		payload["votes"] = append(payload["votes"], map[string]string{"voteId": id})
 	}
 
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("%s | Error When Marshalling JSON When Approving Votes",
-			accountData.AccountAddress.String())
-	}
-
-	url := fmt.Sprintf("https://api-retro-9000.avax.network/api/vote/rounds/%s/confirm-votes",
-		global.Const.RoundID)
-
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("POST")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-		req.Header.Set("content-type", "application/json")
-		req.Header.SetCookie("accessToken", accessToken)
-		req.Header.SetCookie("refreshToken", refreshToken)
-		req.SetBody(payloadBytes)
-
-		resp := fasthttp.AcquireResponse()
-
-		err = client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error When Approving Votes %s",
-				accountData.AccountAddress.String(), err)
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		responseData := &GetVotesResponse{}
-
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response When Approving Votes: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		if responseData.StatusCode != 200 || responseData.Message != "Votes confirmed!" {
-			log.Printf("%s | Wrong Response When Approving Votes: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
+	responseData := &GetVotesResponse{}
 
-		return nil
-	}
+	return c.do(ctx, apiRequest{
+		method:  fasthttp.MethodPost,
+		path:    fmt.Sprintf("/api/vote/rounds/%s/confirm-votes", global.Const.RoundID),
+		payload: payload,
+		action:  "Approving Votes",
+		mutates: true,
+	}, responseData, expectMessage(&responseData.responseStatus, "Votes confirmed!"))
 }
 
-func DeleteVote(
-	client *fasthttp.Client,
-	accountData types.AccountData,
-	accessToken string,
-	refreshToken string,
-	voteID string,
+func (c *Client) DeleteVote(
+	ctx context.Context,
+	projectID string,
 ) error {
-	url := fmt.Sprintf("https://api-retro-9000.avax.network/api/vote/projects/%s/vote",
-		voteID)
-
-	for {
-		req := fasthttp.AcquireRequest()
-		req.SetRequestURI(url)
-		req.Header.SetMethod("DELETE")
-		req.Header.Set("accept", "application/json, text/plain, */*")
-		req.Header.Set("accept-language", "ru,en;q=0.9")
-		req.Header.Set("origin", "https://retro9000.avax.network")
-		req.Header.Set("referer", "https://retro9000.avax.network/")
-		req.Header.Set("content-type", "application/json")
-		req.Header.SetCookie("accessToken", accessToken)
-		req.Header.SetCookie("refreshToken", refreshToken)
-
-		resp := fasthttp.AcquireResponse()
-
-		err := client.Do(req, resp)
-		if err != nil {
-			log.Printf("%s | Error When Deleting Votes %s",
-				accountData.AccountAddress.String(), err)
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		responseData := &GetVotesResponse{}
-
-		if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response When Deleting Votes: %s, response: %s",
-				accountData.AccountAddress.String(), err, string(resp.Body()))
-
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
-		}
-
-		if responseData.StatusCode != 200 || responseData.Message != "Vote deleted!" {
-			log.Printf("%s | Wrong Response When Deleting Votes: %s, response: %s",
-				accountData.AccountAddress.String(), string(resp.Body()), string(resp.Body()))
+	responseData := &GetVotesResponse{}
+
+	return c.do(ctx, apiRequest{
+		method:  fasthttp.MethodDelete,
+		path:    fmt.Sprintf("/api/vote/projects/%s/vote", projectID),
+		action:  "Deleting Votes",
+		mutates: true,
+	}, responseData, expectMessage(&responseData.responseStatus, "Vote deleted!"))
+}
 
-			fasthttp.ReleaseRequest(req)
-			fasthttp.ReleaseResponse(resp)
-			continue
+func expectMessage(
+	status *responseStatus,
+	expected string,
+) func(resp *fasthttp.Response) error {
+	return func(resp *fasthttp.Response) error {
+		if status.Message != expected {
+			return fmt.Errorf("unexpected message %q", status.Message)
 		}
 
-		fasthttp.ReleaseRequest(req)
-		fasthttp.ReleaseResponse(resp)
-
 		return nil
 	}
 }
@@ -0,0 +1,30 @@
+package retroActions
+
+import (
+	"main/pkg/types"
+	"math/rand"
+	"time"
+)
+
+func backoffDelay(
+	retrySettings types.RetrySettings,
+	attempt int,
+) time.Duration {
+	delay := time.Duration(retrySettings.BaseDelayMs) * time.Millisecond
+	maxDelay := time.Duration(retrySettings.MaxDelayMs) * time.Millisecond
+
+	for i := 1; i < attempt && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	// jitter in [delay/2, delay] so parallel accounts don't retry in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,131 @@
+package retroActions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"main/internal/sessionCache"
+	"main/internal/util"
+	"strings"
+	"time"
+)
+
+const sessionKeyMessage = "Retro9000 Voter: session cache key.\nThis signature does not authorize any action."
+
+// Authorize reuses the cached session of the account when the cache is enabled and the session is still
+// valid, refreshes it when only the refresh token is, and signs in otherwise
+func (c *Client) Authorize(
+	ctx context.Context,
+) error {
+	session := sessionCache.Load(c.cacheKey(), c.accountData.AccountAddress.String(), c.baseURL)
+
+	switch {
+	case session == nil:
+	case session.AccessValid():
+		c.restoreSession(session)
+		log.Printf("%s | Reusing Cached Session", c.accountData.AccountAddress.String())
+		return nil
+	case session.RefreshValid():
+		c.restoreSession(session)
+
+		if err := c.RefreshSession(ctx); err == nil {
+			log.Printf("%s | Refreshed Cached Session", c.accountData.AccountAddress.String())
+			return nil
+		}
+
+		c.restoreSession(&sessionCache.Session{})
+	}
+
+	return c.Login(ctx)
+}
+
+// cacheKey derives the session cache key from a signature of a fixed message, so it works with any signer.
+// A signer with non-deterministic signatures only gets cache misses
+func (c *Client) cacheKey() []byte {
+	if c.sessionKey != nil || c.accountData.Signer == nil || !sessionCache.Enabled() {
+		return c.sessionKey
+	}
+
+	signature, err := c.accountData.Signer.SignPersonalMessage([]byte(sessionKeyMessage))
+	if err != nil {
+		log.Warnf("%s | Session Cache Is Not Used: %v", c.accountData.AccountAddress.String(), err)
+		return nil
+	}
+
+	keyHash := sha256.Sum256(signature)
+	c.sessionKey = keyHash[:]
+
+	return c.sessionKey
+}
+
+func (c *Client) restoreSession(session *sessionCache.Session) {
+	c.accessToken = session.AccessToken
+	c.refreshToken = session.RefreshToken
+	c.accessExpiresAt = session.AccessExpiresAt
+	c.refreshExpiresAt = session.RefreshExpiresAt
+}
+
+func (c *Client) storeSession(
+	resp *fasthttp.Response,
+	requireRefreshToken bool,
+) error {
+	accessTokenCookie := resp.Header.PeekCookie("accessToken")
+	refreshTokenCookie := resp.Header.PeekCookie("refreshToken")
+
+	if accessTokenCookie == nil || (requireRefreshToken && refreshTokenCookie == nil) {
+		return errNoSessionCookies
+	}
+
+	c.accessToken = util.ExtractCookieValue(string(accessTokenCookie), "accessToken")
+	c.accessExpiresAt = tokenExpiry(c.accessToken, accessTokenCookie)
+
+	if refreshTokenCookie != nil {
+		c.refreshToken = util.ExtractCookieValue(string(refreshTokenCookie), "refreshToken")
+		c.refreshExpiresAt = tokenExpiry(c.refreshToken, refreshTokenCookie)
+	}
+
+	sessionCache.Save(c.cacheKey(), sessionCache.Session{
+		Address:          c.accountData.AccountAddress.String(),
+		BaseURL:          c.baseURL,
+		AccessToken:      c.accessToken,
+		RefreshToken:     c.refreshToken,
+		AccessExpiresAt:  c.accessExpiresAt,
+		RefreshExpiresAt: c.refreshExpiresAt,
+	})
+
+	return nil
+}
+
+// tokenExpiry takes the exp claim when the token is a JWT and the cookie expiry otherwise
+func tokenExpiry(token string, rawCookie []byte) time.Time {
+	if parts := strings.Split(token, "."); len(parts) == 3 {
+		var claims struct {
+			Exp int64 `json:"exp"`
+		}
+
+		if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil &&
+			json.Unmarshal(payload, &claims) == nil && claims.Exp > 0 {
+			return time.Unix(claims.Exp, 0)
+		}
+	}
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+
+	if err := cookie.ParseBytes(rawCookie); err != nil {
+		return time.Time{}
+	}
+
+	if cookie.MaxAge() > 0 {
+		return time.Now().Add(time.Duration(cookie.MaxAge()) * time.Second)
+	}
+
+	if expire := cookie.Expire(); !expire.Equal(fasthttp.CookieExpireUnlimited) {
+		return expire
+	}
+
+	return time.Time{}
+}
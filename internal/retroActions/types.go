@@ -1,9 +1,21 @@
 package retroActions
 
-type getSignTextResponse struct {
+type apiResponse interface {
+	status() *responseStatus
+}
+
+type responseStatus struct {
 	StatusCode int    `json:"statusCode"`
 	Message    string `json:"message"`
-	Data       struct {
+}
+
+func (r *responseStatus) status() *responseStatus {
+	return r
+}
+
+type getSignTextResponse struct {
+	responseStatus
+	Data struct {
 		Nonce string `json:"nonce"`
 	} `json:"data"`
 	Metadata interface{} `json:"metadata"`
@@ -11,9 +23,8 @@ type getSignTextResponse struct {
 }
 
 type doLoginResponse struct {
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
-	Data       struct {
+	responseStatus
+	Data struct {
 		TotalReferralPoints interface{} `json:"totalReferralPoints"`
 		User                struct {
 			ChillFactor   int64  `json:"chill_factor"`
@@ -25,36 +36,55 @@ type doLoginResponse struct {
 	Error    interface{} `json:"error"`
 }
 
+type refreshSessionResponse struct {
+	responseStatus
+	Data     interface{} `json:"data"`
+	Metadata interface{} `json:"metadata"`
+	Error    interface{} `json:"error"`
+}
+
 type GetVotesResponse struct {
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
-	Data       struct {
-		Id                 string `json:"id"`
-		TotalEligibleVotes int64  `json:"total_eligible_votes"`
-		UsedVotes          int64  `json:"used_votes"`
-		Votes              []struct {
-			Id          string `json:"id"`
-			IsConfirmed bool   `json:"is_confirmed"`
-			Project     struct {
-				BannerUrl   interface{} `json:"banner_url"`
-				Description string      `json:"desription"`
-				Id          string      `json:"id"`
-				LogoURL     string      `json:"logo_url"`
-				Name        string      `json:"name"`
-				Status      string      `json:"status"`
-				TotalVotes  int64       `json:"total_votes"`
-			} `json:"project"`
-			VoteCount int64 `json:"vote_count"`
-		} `json:"votes"`
+	responseStatus
+	Data struct {
+		Id                 string       `json:"id"`
+		TotalEligibleVotes int64        `json:"total_eligible_votes"`
+		UsedVotes          int64        `json:"used_votes"`
+		Votes              []BallotVote `json:"votes"`
 	} `json:"data"`
 	Metadata interface{} `json:"metadata"`
 	Error    interface{} `json:"error"`
 }
 
+type BallotVote struct {
+	Id          string `json:"id"`
+	IsConfirmed bool   `json:"is_confirmed"`
+	Project     struct {
+		BannerUrl   interface{} `json:"banner_url"`
+		Description string      `json:"desription"`
+		Id          string      `json:"id"`
+		LogoURL     string      `json:"logo_url"`
+		Name        string      `json:"name"`
+		Status      string      `json:"status"`
+		TotalVotes  int64       `json:"total_votes"`
+	} `json:"project"`
+	VoteCount int64 `json:"vote_count"`
+}
+
+func (r *GetVotesResponse) NotConfirmedIDs() []string {
+	var notConfirmedVotes []string
+
+	for _, voteData := range r.Data.Votes {
+		if !voteData.IsConfirmed {
+			notConfirmedVotes = append(notConfirmedVotes, voteData.Id)
+		}
+	}
+
+	return notConfirmedVotes
+}
+
 type GetBallotsResponse struct {
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
-	Data       struct {
+	responseStatus
+	Data struct {
 		Id                 string `json:"id"`
 		TotalEligibleVotes int64  `json:"total_eligible_votes"`
 		UsedVotes          int64  `json:"used_votes"`
@@ -64,10 +94,9 @@ type GetBallotsResponse struct {
 }
 
 type getProjectsListResponse struct {
-	StatusCode int           `json:"statusCode"`
-	Message    string        `json:"message"`
-	Data       []ProjectData `json:"data"`
-	Metadata   struct {
+	responseStatus
+	Data     []ProjectData `json:"data"`
+	Metadata struct {
 		Total       int  `json:"total"`
 		LastPage    int  `json:"lastPage"`
 		CurrentPage int  `json:"currentPage"`
@@ -107,9 +136,8 @@ type ProjectData struct {
 }
 
 type doVoteResponse struct {
-	StatusCode int         `json:"statusCode"`
-	Message    string      `json:"message"`
-	Data       interface{} `json:"data"`
-	Metadata   interface{} `json:"metadata"`
-	Error      *string     `json:"error"`
+	responseStatus
+	Data     interface{} `json:"data"`
+	Metadata interface{} `json:"metadata"`
+	Error    *string     `json:"error"`
 }
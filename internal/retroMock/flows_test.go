@@ -0,0 +1,393 @@
+package retroMock_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"main/internal/report"
+	"main/internal/retroActions"
+	"main/internal/retroMock"
+	"main/internal/util"
+	"main/internal/voter"
+	"main/internal/voterDeleter"
+	"main/internal/voterReconciler"
+	"main/pkg/global"
+	"main/pkg/signer"
+	"main/pkg/types"
+	util2 "main/pkg/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// setup starts the mock with approved projects and one account that votes for all of them equally.
+// Every test gets its own round, so the shared projects list of an earlier test is not reused
+func setup(t *testing.T, projectsCount int) (*retroMock.Server, types.AccountData) {
+	t.Helper()
+
+	roundID := "round-" + t.Name()
+	projects := make([]retroActions.ProjectData, 0, projectsCount)
+
+	for i := 1; i <= projectsCount; i++ {
+		projects = append(projects, retroActions.ProjectData{
+			ID:     fmt.Sprintf("project-%d", i),
+			Name:   fmt.Sprintf("Project %d", i),
+			Status: "approved",
+		})
+	}
+
+	server := retroMock.NewServer(roundID, projects)
+	t.Cleanup(server.Close)
+
+	global.Const.RoundID = roundID
+	global.DryRun = false
+	global.Settings.APIBaseURL = server.URL
+	global.Settings.Retry = types.RetrySettings{MaxAttempts: 3, BaseDelayMs: 1, MaxDelayMs: 5}
+	global.Settings.Distribution = types.DistributionSettings{
+		Strategy:    voter.StrategyEqual,
+		Seed:        1,
+		MinProjects: projectsCount,
+		MaxProjects: projectsCount,
+	}
+	global.Settings.ProjectFilter = types.ProjectFilter{}
+	util2.ProxiesCycler = &util2.ProxyCycler{}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountData := types.AccountData{
+		AccountAddress: crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey:     privateKey,
+		Signer:         signer.NewLocalKey(privateKey),
+	}
+	global.AccountsList = []types.AccountData{accountData}
+
+	if err = voter.InitDistribution(); err != nil {
+		t.Fatal(err)
+	}
+
+	return server, accountData
+}
+
+func doVotes(accountData types.AccountData) error {
+	return voter.DoVotes(context.Background(), accountData, "", &report.AccountReport{})
+}
+
+// ballot returns the vote counts by project and how many of the votes are confirmed
+func ballot(server *retroMock.Server, accountData types.AccountData) (map[string]int64, int) {
+	votes := map[string]int64{}
+	confirmed := 0
+
+	for _, vote := range server.Votes(accountData.AccountAddress) {
+		votes[vote.ProjectID] = vote.Count
+
+		if vote.Confirmed {
+			confirmed++
+		}
+	}
+
+	return votes, confirmed
+}
+
+func TestDoVotesFollowsPagination(t *testing.T) {
+	server, accountData := setup(t, 25)
+	server.MaxPerPage = 10
+
+	if err := doVotes(accountData); err != nil {
+		t.Fatalf("DoVotes: %v", err)
+	}
+
+	if requests := server.Requests(retroMock.RouteSubmissions); requests != 3 {
+		t.Errorf("submissions requested %d times, want 3 pages", requests)
+	}
+
+	votes, confirmed := ballot(server, accountData)
+
+	if len(votes) != 25 || confirmed != 25 {
+		t.Errorf("ballot has %d votes, %d confirmed, want 25 confirmed", len(votes), confirmed)
+	}
+}
+
+func TestDoVotesRetriesRateLimitedVote(t *testing.T) {
+	server, accountData := setup(t, 5)
+	server.InjectFailure(retroMock.RouteVote, retroMock.Failure{StatusCode: 429, Message: "Too Many Requests", Times: 2})
+
+	if err := doVotes(accountData); err != nil {
+		t.Fatalf("DoVotes: %v", err)
+	}
+
+	if requests := server.Requests(retroMock.RouteVote); requests != 7 {
+		t.Errorf("vote requested %d times, want 5 votes and 2 retries", requests)
+	}
+
+	if votes, confirmed := ballot(server, accountData); len(votes) != 5 || confirmed != 5 {
+		t.Errorf("ballot has %d votes, %d confirmed, want 5 confirmed", len(votes), confirmed)
+	}
+}
+
+func TestDoVotesRefreshesExpiredSession(t *testing.T) {
+	server, accountData := setup(t, 5)
+	server.InjectFailure(retroMock.RouteVote, retroMock.Failure{StatusCode: 401, Message: "Access token expired", Times: 1})
+
+	if err := doVotes(accountData); err != nil {
+		t.Fatalf("DoVotes: %v", err)
+	}
+
+	if requests := server.Requests(retroMock.RouteRefresh); requests != 1 {
+		t.Errorf("session refreshed %d times, want 1", requests)
+	}
+
+	if requests := server.Requests(retroMock.RouteLogin); requests != 1 {
+		t.Errorf("signed in %d times, want 1", requests)
+	}
+
+	if votes, confirmed := ballot(server, accountData); len(votes) != 5 || confirmed != 5 {
+		t.Errorf("ballot has %d votes, %d confirmed, want 5 confirmed", len(votes), confirmed)
+	}
+}
+
+func TestDoVotesSignsInAgainWhenRefreshFails(t *testing.T) {
+	server, accountData := setup(t, 5)
+	server.InjectFailure(retroMock.RouteVote, retroMock.Failure{StatusCode: 401, Message: "Access token expired", Times: 1})
+	server.InjectFailure(retroMock.RouteRefresh, retroMock.Failure{StatusCode: 401, Message: "Refresh token expired", Times: 1})
+
+	if err := doVotes(accountData); err != nil {
+		t.Fatalf("DoVotes: %v", err)
+	}
+
+	if requests := server.Requests(retroMock.RouteLogin); requests != 2 {
+		t.Errorf("signed in %d times, want 2", requests)
+	}
+
+	if votes, confirmed := ballot(server, accountData); len(votes) != 5 || confirmed != 5 {
+		t.Errorf("ballot has %d votes, %d confirmed, want 5 confirmed", len(votes), confirmed)
+	}
+}
+
+func TestDoVotesFailsAccountOnRejectedVote(t *testing.T) {
+	server, accountData := setup(t, 5)
+	server.InjectFailure(retroMock.RouteVote, retroMock.Failure{StatusCode: 400, Message: "Invalid vote", Times: 1})
+
+	err := doVotes(accountData)
+
+	var batchErr *retroActions.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errs) != 1 {
+		t.Fatalf("DoVotes returned %v, want one failed vote", err)
+	}
+
+	if requests := server.Requests(retroMock.RouteConfirmVotes); requests != 0 {
+		t.Errorf("votes confirmed %d times, the account must stop before confirming", requests)
+	}
+
+	if votes, _ := ballot(server, accountData); len(votes) != 4 {
+		t.Errorf("ballot has %d votes, want the 4 accepted votes", len(votes))
+	}
+}
+
+func TestDoVotesStopsOnClosedBallot(t *testing.T) {
+	server, accountData := setup(t, 5)
+	server.InjectFailure(retroMock.RouteVote, retroMock.Failure{StatusCode: 400, Message: "Voting is closed"})
+
+	err := doVotes(accountData)
+
+	var fatalErr *retroActions.FatalError
+	if !errors.As(err, &fatalErr) || fatalErr.Kind != retroActions.KindBallotClosed {
+		t.Fatalf("DoVotes returned %v, want a closed ballot error", err)
+	}
+
+	if requests := server.Requests(retroMock.RouteVote); requests != 1 {
+		t.Errorf("vote requested %d times, a closed ballot must stop the account", requests)
+	}
+}
+
+func TestDoVotesFinishesStartedStepsAfterShutdownRequest(t *testing.T) {
+	server, accountData := setup(t, 5)
+	server.InjectFailure(retroMock.RouteVote, retroMock.Failure{StatusCode: 429, Message: "Too Many Requests", Times: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the shutdown is requested while the first vote is in flight
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/vote") {
+			cancel()
+		}
+
+		server.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	global.Settings.APIBaseURL = proxy.URL
+
+	// the account stops at the next step boundary, after its votes are confirmed
+	if err := voter.DoVotes(ctx, accountData, "", &report.AccountReport{}); !errors.Is(err, util.ErrInterrupted) {
+		t.Fatalf("DoVotes returned %v, want an account interrupted before verifying", err)
+	}
+
+	if votes, confirmed := ballot(server, accountData); len(votes) != 5 || confirmed != 5 {
+		t.Errorf("ballot has %d votes, %d confirmed, want every started vote cast and confirmed", len(votes), confirmed)
+	}
+}
+
+func TestDoVotesStopsBeforeVotingAfterShutdownRequest(t *testing.T) {
+	server, accountData := setup(t, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := voter.DoVotes(ctx, accountData, "", &report.AccountReport{})
+
+	if !errors.Is(err, util.ErrInterrupted) {
+		t.Fatalf("DoVotes returned %v, want an interrupted account", err)
+	}
+
+	if requests := server.Requests(retroMock.RouteVote); requests != 0 {
+		t.Errorf("vote requested %d times, want none", requests)
+	}
+}
+
+func TestDeleteVotesRetriesRateLimitedDeletion(t *testing.T) {
+	server, accountData := setup(t, 5)
+
+	if err := doVotes(accountData); err != nil {
+		t.Fatalf("DoVotes: %v", err)
+	}
+
+	if err := voterDeleter.InitSelection(voterDeleter.Selection{}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectFailure(retroMock.RouteDeleteVote, retroMock.Failure{StatusCode: 429, Message: "Too Many Requests", Times: 1})
+
+	if err := voterDeleter.DeleteVotes(context.Background(), accountData, "", &report.AccountReport{}); err != nil {
+		t.Fatalf("DeleteVotes: %v", err)
+	}
+
+	if votes, _ := ballot(server, accountData); len(votes) != 0 {
+		t.Errorf("ballot has %d votes left, want none", len(votes))
+	}
+}
+
+func TestDeleteVotesFailsAccountOnRejectedDeletion(t *testing.T) {
+	server, accountData := setup(t, 5)
+
+	if err := doVotes(accountData); err != nil {
+		t.Fatalf("DoVotes: %v", err)
+	}
+
+	if err := voterDeleter.InitSelection(voterDeleter.Selection{}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectFailure(retroMock.RouteDeleteVote, retroMock.Failure{StatusCode: 400, Message: "Vote not found", Times: 1})
+
+	err := voterDeleter.DeleteVotes(context.Background(), accountData, "", &report.AccountReport{})
+
+	var batchErr *retroActions.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errs) != 1 {
+		t.Fatalf("DeleteVotes returned %v, want one failed deletion", err)
+	}
+
+	if votes, _ := ballot(server, accountData); len(votes) != 1 {
+		t.Errorf("ballot has %d votes left, want the rejected one", len(votes))
+	}
+}
+
+func TestReconcileConvergesToPlan(t *testing.T) {
+	server, accountData := setup(t, 5)
+
+	if err := doVotes(accountData); err != nil {
+		t.Fatalf("DoVotes: %v", err)
+	}
+
+	planPath := filepath.Join(t.TempDir(), "plan.csv")
+	plan := fmt.Sprintf("account,project,votes\n%[1]s,project-1,30\n%[1]s,Project 2,10%%\n", accountData.AccountAddress.String())
+
+	if err := os.WriteFile(planPath, []byte(plan), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := voterReconciler.InitPlan(planPath); err != nil {
+		t.Fatalf("InitPlan: %v", err)
+	}
+
+	if err := voterReconciler.Reconcile(context.Background(), accountData, "", &report.AccountReport{}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	votes, confirmed := ballot(server, accountData)
+
+	if len(votes) != 2 || votes["project-1"] != 30 || votes["project-2"] != 10 || confirmed != 2 {
+		t.Fatalf("ballot is %v with %d confirmed, want project-1: 30 and project-2: 10 confirmed", votes, confirmed)
+	}
+
+	// project-1 and project-2 stay on the ballot, only their counts change
+	if deletions := server.Requests(retroMock.RouteDeleteVote); deletions != 3 {
+		t.Errorf("Reconcile sent %d deletions, want 3 for the projects left out of the plan", deletions)
+	}
+
+	changes := server.Requests(retroMock.RouteVote) + server.Requests(retroMock.RouteDeleteVote)
+
+	if err := voterReconciler.Reconcile(context.Background(), accountData, "", &report.AccountReport{}); err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+
+	if repeated := server.Requests(retroMock.RouteVote) + server.Requests(retroMock.RouteDeleteVote); repeated != changes {
+		t.Errorf("second Reconcile sent %d changes, want none", repeated-changes)
+	}
+}
+
+func TestReconcileRejectsPlanBeforeAnyChange(t *testing.T) {
+	server, accountData := setup(t, 5)
+
+	planPath := filepath.Join(t.TempDir(), "plan.csv")
+	plan := fmt.Sprintf("account,project,votes\n%[1]s,project-1,30\n%[1]s,Unknown Project,10\n", accountData.AccountAddress.String())
+
+	if err := os.WriteFile(planPath, []byte(plan), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := voterReconciler.InitPlan(planPath); err == nil {
+		t.Fatal("InitPlan accepted a plan with an unknown project")
+	}
+
+	if requests := server.Requests(retroMock.RouteVote); requests != 0 {
+		t.Errorf("vote requested %d times, want none", requests)
+	}
+}
+
+func TestVotePlanRejectsOverAllocationBeforeAnyVote(t *testing.T) {
+	server, accountData := setup(t, 5)
+
+	planPath := filepath.Join(t.TempDir(), "plan.csv")
+	plan := fmt.Sprintf("account,project,votes\n%[1]s,project-1,60\n%[1]s,project-2,50%%\n", accountData.AccountAddress.String())
+
+	if err := os.WriteFile(planPath, []byte(plan), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := voter.InitPlan(planPath, false); err == nil {
+		t.Fatal("InitPlan accepted a plan that allocates 110 of 100 votes")
+	}
+
+	if requests := server.Requests(retroMock.RouteBallot); requests != 1 {
+		t.Errorf("ballot read %d times, want 1 read by the plan check", requests)
+	}
+
+	if requests := server.Requests(retroMock.RouteVote); requests != 0 {
+		t.Errorf("vote requested %d times, want none", requests)
+	}
+}
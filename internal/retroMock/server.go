@@ -0,0 +1,700 @@
+package retroMock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"main/internal/retroActions"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Route string
+
+const (
+	RouteNonce        Route = "get-nonce"
+	RouteLogin        Route = "login"
+	RouteRefresh      Route = "refresh"
+	RouteSubmissions  Route = "submissions"
+	RouteBallot       Route = "ballot"
+	RouteBallotVotes  Route = "ballot-votes"
+	RouteVote         Route = "vote"
+	RouteConfirmVotes Route = "confirm-votes"
+	RouteDeleteVote   Route = "delete-vote"
+)
+
+type FailureKind int
+
+const (
+	FailStatus FailureKind = iota
+	FailMalformedJSON
+	FailMissingCookies
+)
+
+// Failure replaces the next Times responses of a route, Times <= 0 fails every request.
+// FailMissingCookies only affects login and refresh responses, which succeed without session cookies
+type Failure struct {
+	Kind       FailureKind
+	StatusCode int
+	Message    string
+	Times      int
+}
+
+type Vote struct {
+	ID        string
+	ProjectID string
+	Count     int64
+	Confirmed bool
+}
+
+type ballot struct {
+	id    string
+	votes []*Vote
+}
+
+// Server is an in-process fake of the Retro9000 API with per-address ballots
+type Server struct {
+	URL                  string
+	RoundID              string
+	DefaultEligibleVotes int64
+	MaxPerPage           int // caps perPage of the submissions list like the real API, 0 - no cap
+	// SignInMessage builds the text returned by get-nonce, nil returns an EIP-4361 message of the public site
+	SignInMessage func(address common.Address, nonce string) string
+
+	httpServer    *httptest.Server
+	mu            sync.Mutex
+	projects      []retroActions.ProjectData
+	nonces        map[common.Address]string
+	sessions      map[string]common.Address
+	refreshTokens map[string]common.Address
+	eligibleVotes map[common.Address]int64
+	ballots       map[common.Address]*ballot
+	failures      map[Route][]*Failure
+	requests      map[Route]int
+}
+
+// NewServer starts the fake on a random local port, URL is set to its address
+func NewServer(
+	roundID string,
+	projects []retroActions.ProjectData,
+) *Server {
+	s := NewHandler(roundID, projects)
+
+	s.httpServer = httptest.NewServer(s)
+	s.URL = s.httpServer.URL
+
+	return s
+}
+
+// NewHandler returns the fake without a listener, serve it with any http.Server (see cmd/retroMock)
+func NewHandler(
+	roundID string,
+	projects []retroActions.ProjectData,
+) *Server {
+	return &Server{
+		RoundID:              roundID,
+		DefaultEligibleVotes: 100,
+		projects:             projects,
+		nonces:               map[common.Address]string{},
+		sessions:             map[string]common.Address{},
+		refreshTokens:        map[string]common.Address{},
+		eligibleVotes:        map[common.Address]int64{},
+		ballots:              map[common.Address]*ballot{},
+		failures:             map[Route][]*Failure{},
+		requests:             map[Route]int{},
+	}
+}
+
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+func (s *Server) SetEligibleVotes(address common.Address, votes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.eligibleVotes[address] = votes
+}
+
+// ExpireSessions invalidates every access token, refresh tokens keep working
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions = map[string]common.Address{}
+}
+
+// RevokeRefreshTokens invalidates every refresh token, so only a new sign-in restores the session
+func (s *Server) RevokeRefreshTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshTokens = map[string]common.Address{}
+}
+
+func (s *Server) InjectFailure(route Route, failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[route] = append(s.failures[route], &failure)
+}
+
+func (s *Server) Requests(route Route) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[route]
+}
+
+// Votes returns a copy of the address ballot, nil when no ballot was opened
+func (s *Server) Votes(address common.Address) []Vote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.ballots[address]
+	if !ok {
+		return nil
+	}
+
+	votes := make([]Vote, 0, len(b.votes))
+	for _, v := range b.votes {
+		votes = append(votes, *v)
+	}
+
+	return votes
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, params, ok := matchRoute(r.Method, r.URL.Path)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, "Cannot "+r.Method+" "+r.URL.Path, nil, nil)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[route]++
+
+	dropCookies := false
+
+	if failure := s.nextFailure(route); failure != nil {
+		switch failure.Kind {
+		case FailMalformedJSON:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"statusCode":200,"data":`))
+			return
+		case FailStatus:
+			writeJSON(w, failure.StatusCode, failure.Message, nil, nil)
+			return
+		case FailMissingCookies:
+			dropCookies = true
+		}
+	}
+
+	if roundID, ok := params["round"]; ok && roundID != s.RoundID {
+		writeJSON(w, http.StatusNotFound, "Round not found!", nil, nil)
+		return
+	}
+
+	switch route {
+	case RouteNonce:
+		s.handleNonce(w, params["address"])
+	case RouteLogin:
+		s.handleLogin(w, r, dropCookies)
+	case RouteRefresh:
+		s.handleRefresh(w, r, dropCookies)
+	case RouteSubmissions:
+		s.handleSubmissions(w, r)
+	default:
+		address, authorized := s.authorize(r)
+		if !authorized {
+			writeJSON(w, http.StatusUnauthorized, "Unauthorized", nil, nil)
+			return
+		}
+
+		switch route {
+		case RouteBallot:
+			s.handleBallot(w, address)
+		case RouteBallotVotes:
+			s.handleBallotVotes(w, address)
+		case RouteVote:
+			s.handleVote(w, r, address, params["project"])
+		case RouteConfirmVotes:
+			s.handleConfirmVotes(w, r, address)
+		case RouteDeleteVote:
+			s.handleDeleteVote(w, address, params["project"])
+		}
+	}
+}
+
+func (s *Server) nextFailure(route Route) *Failure {
+	failures := s.failures[route]
+	if len(failures) == 0 {
+		return nil
+	}
+
+	failure := failures[0]
+	if failure.Times > 0 {
+		failure.Times--
+		if failure.Times == 0 {
+			s.failures[route] = failures[1:]
+		}
+	}
+
+	return failure
+}
+
+func matchRoute(method string, path string) (Route, map[string]string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case method == http.MethodGet && len(parts) == 4 && parts[1] == "auth" && parts[2] == "get-nonce":
+		return RouteNonce, map[string]string{"address": parts[3]}, true
+	case method == http.MethodPost && len(parts) == 3 && parts[1] == "auth" && parts[2] == "login":
+		return RouteLogin, nil, true
+	case method == http.MethodPost && len(parts) == 3 && parts[1] == "auth" && parts[2] == "refresh":
+		return RouteRefresh, nil, true
+	case method == http.MethodGet && len(parts) == 4 && parts[1] == "rounds" && parts[3] == "submissions":
+		return RouteSubmissions, map[string]string{"round": parts[2]}, true
+	case method == http.MethodGet && len(parts) == 5 && parts[1] == "vote" && parts[4] == "ballot":
+		return RouteBallot, map[string]string{"round": parts[3]}, true
+	case method == http.MethodGet && len(parts) == 5 && parts[1] == "vote" && parts[4] == "ballot-votes":
+		return RouteBallotVotes, map[string]string{"round": parts[3]}, true
+	case method == http.MethodPost && len(parts) == 5 && parts[1] == "vote" && parts[4] == "confirm-votes":
+		return RouteConfirmVotes, map[string]string{"round": parts[3]}, true
+	case method == http.MethodPost && len(parts) == 7 && parts[1] == "vote" && parts[4] == "projects" && parts[6] == "vote":
+		return RouteVote, map[string]string{"round": parts[3], "project": parts[5]}, true
+	case method == http.MethodDelete && len(parts) == 5 && parts[1] == "vote" && parts[2] == "projects" && parts[4] == "vote":
+		return RouteDeleteVote, map[string]string{"project": parts[3]}, true
+	}
+
+	return "", nil, false
+}
+
+// DefaultSignInMessage is an EIP-4361 message the default sign_in settings accept
+func DefaultSignInMessage(address common.Address, nonce string) string {
+	return fmt.Sprintf("retro9000.avax.network wants you to sign in with your Ethereum account:\n%s\n\n"+
+		"Sign in to Retro9000.\n\nURI: https://retro9000.avax.network\nVersion: 1\nChain ID: 43114\n"+
+		"Nonce: %s\nIssued At: %s", address.Hex(), nonce, time.Now().UTC().Format(time.RFC3339))
+}
+
+func (s *Server) handleNonce(w http.ResponseWriter, addressHex string) {
+	if !common.IsHexAddress(addressHex) {
+		writeJSON(w, http.StatusBadRequest, "Invalid wallet address", nil, nil)
+		return
+	}
+
+	signInMessage := s.SignInMessage
+	if signInMessage == nil {
+		signInMessage = DefaultSignInMessage
+	}
+
+	nonce := signInMessage(common.HexToAddress(addressHex), randomHex(16))
+	s.nonces[common.HexToAddress(addressHex)] = nonce
+
+	writeJSON(w, http.StatusOK, "Nonce generated", map[string]string{"nonce": nonce}, nil)
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request, dropCookies bool) {
+	var payload struct {
+		WalletAddress string `json:"walletAddress"`
+		Signature     string `json:"signature"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || !common.IsHexAddress(payload.WalletAddress) {
+		writeJSON(w, http.StatusBadRequest, "Invalid payload", nil, nil)
+		return
+	}
+
+	address := common.HexToAddress(payload.WalletAddress)
+	nonce, ok := s.nonces[address]
+
+	if !ok || !verifySignature(address, nonce, payload.Signature) {
+		writeJSON(w, http.StatusUnauthorized, "Invalid signature", nil, nil)
+		return
+	}
+
+	delete(s.nonces, address)
+
+	writeJSON(w, http.StatusOK, "Login successful", map[string]interface{}{
+		"totalReferralPoints": nil,
+		"user": map[string]interface{}{
+			"chill_factor":   0,
+			"referral_code":  "",
+			"wallet_address": strings.ToLower(address.Hex()),
+		},
+	}, s.newSession(address, dropCookies))
+}
+
+// handleRefresh rotates the refresh token, the old one cannot be used again
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request, dropCookies bool) {
+	cookie, err := r.Cookie("refreshToken")
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, "Refresh token missing", nil, nil)
+		return
+	}
+
+	address, ok := s.refreshTokens[cookie.Value]
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, "Refresh token expired", nil, nil)
+		return
+	}
+
+	delete(s.refreshTokens, cookie.Value)
+
+	writeJSON(w, http.StatusOK, "Token refreshed", nil, s.newSession(address, dropCookies))
+}
+
+func (s *Server) newSession(address common.Address, dropCookies bool) []*http.Cookie {
+	accessToken := randomHex(32)
+	refreshToken := randomHex(32)
+	s.sessions[accessToken] = address
+	s.refreshTokens[refreshToken] = address
+
+	if dropCookies {
+		return nil
+	}
+
+	return []*http.Cookie{
+		{Name: "accessToken", Value: accessToken, Path: "/", HttpOnly: true},
+		{Name: "refreshToken", Value: refreshToken, Path: "/", HttpOnly: true},
+	}
+}
+
+func (s *Server) handleSubmissions(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("perPage"))
+
+	if page < 1 {
+		page = 1
+	}
+
+	if perPage < 1 {
+		perPage = 10
+	}
+
+	if s.MaxPerPage > 0 && perPage > s.MaxPerPage {
+		perPage = s.MaxPerPage
+	}
+
+	total := len(s.projects)
+	lastPage := (total + perPage - 1) / perPage
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+
+	metadata := map[string]interface{}{
+		"total":       total,
+		"lastPage":    lastPage,
+		"currentPage": page,
+		"perPage":     perPage,
+		"prev":        nil,
+		"next":        nil,
+	}
+
+	if page > 1 {
+		metadata["prev"] = page - 1
+	}
+
+	if page < lastPage {
+		metadata["next"] = page + 1
+	}
+
+	writeResponse(w, http.StatusOK, map[string]interface{}{
+		"statusCode": http.StatusOK,
+		"message":    "Submissions fetched",
+		"data":       s.projects[start:end],
+		"metadata":   metadata,
+		"error":      nil,
+	}, nil)
+}
+
+func (s *Server) handleBallot(w http.ResponseWriter, address common.Address) {
+	b := s.openBallot(address)
+	writeJSON(w, http.StatusOK, "Ballot fetched", s.ballotData(address, b, false), nil)
+}
+
+func (s *Server) handleBallotVotes(w http.ResponseWriter, address common.Address) {
+	b, ok := s.ballots[address]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, "Ballot not found!", nil, nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, "Ballot votes fetched", s.ballotData(address, b, true), nil)
+}
+
+func (s *Server) handleVote(w http.ResponseWriter, r *http.Request, address common.Address, projectID string) {
+	var payload struct {
+		VoteCount int64 `json:"voteCount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.VoteCount <= 0 {
+		writeJSON(w, http.StatusBadRequest, "Invalid vote count", nil, nil)
+		return
+	}
+
+	if s.findProject(projectID) == nil {
+		writeJSON(w, http.StatusNotFound, "Project not found!", nil, nil)
+		return
+	}
+
+	b := s.openBallot(address)
+	existing := b.findVote(projectID)
+
+	usedVotes := b.usedVotes()
+	if existing != nil {
+		usedVotes -= existing.Count
+	}
+
+	if usedVotes+payload.VoteCount > s.eligible(address) {
+		writeJSON(w, http.StatusBadRequest, "Not enough votes!", nil, nil)
+		return
+	}
+
+	if existing != nil {
+		existing.Count = payload.VoteCount
+		existing.Confirmed = false
+	} else {
+		b.votes = append(b.votes, &Vote{ID: randomHex(12), ProjectID: projectID, Count: payload.VoteCount})
+	}
+
+	writeJSON(w, http.StatusOK, "Voting successful!", nil, nil)
+}
+
+func (s *Server) handleConfirmVotes(w http.ResponseWriter, r *http.Request, address common.Address) {
+	var payload struct {
+		Votes []struct {
+			VoteID string `json:"voteId"`
+		} `json:"votes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || len(payload.Votes) == 0 {
+		writeJSON(w, http.StatusBadRequest, "No votes to confirm", nil, nil)
+		return
+	}
+
+	b, ok := s.ballots[address]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, "Ballot not found!", nil, nil)
+		return
+	}
+
+	votes := make([]*Vote, 0, len(payload.Votes))
+	for _, requested := range payload.Votes {
+		var found *Vote
+		for _, v := range b.votes {
+			if v.ID == requested.VoteID {
+				found = v
+			}
+		}
+
+		if found == nil {
+			writeJSON(w, http.StatusBadRequest, "Vote not found!", nil, nil)
+			return
+		}
+
+		votes = append(votes, found)
+	}
+
+	for _, v := range votes {
+		v.Confirmed = true
+	}
+
+	writeJSON(w, http.StatusOK, "Votes confirmed!", nil, nil)
+}
+
+func (s *Server) handleDeleteVote(w http.ResponseWriter, address common.Address, projectID string) {
+	b, ok := s.ballots[address]
+	if !ok || b.findVote(projectID) == nil {
+		writeJSON(w, http.StatusNotFound, "Vote not found!", nil, nil)
+		return
+	}
+
+	for i, v := range b.votes {
+		if v.ProjectID == projectID {
+			b.votes = append(b.votes[:i], b.votes[i+1:]...)
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, "Vote deleted!", nil, nil)
+}
+
+func (s *Server) authorize(r *http.Request) (common.Address, bool) {
+	cookie, err := r.Cookie("accessToken")
+	if err != nil {
+		return common.Address{}, false
+	}
+
+	address, ok := s.sessions[cookie.Value]
+
+	return address, ok
+}
+
+func (s *Server) eligible(address common.Address) int64 {
+	if votes, ok := s.eligibleVotes[address]; ok {
+		return votes
+	}
+
+	return s.DefaultEligibleVotes
+}
+
+func (s *Server) openBallot(address common.Address) *ballot {
+	b, ok := s.ballots[address]
+	if !ok {
+		b = &ballot{id: randomHex(12)}
+		s.ballots[address] = b
+	}
+
+	return b
+}
+
+func (s *Server) findProject(projectID string) *retroActions.ProjectData {
+	for i := range s.projects {
+		if s.projects[i].ID == projectID {
+			return &s.projects[i]
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) ballotData(address common.Address, b *ballot, withVotes bool) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":                   b.id,
+		"total_eligible_votes": s.eligible(address),
+		"used_votes":           b.usedVotes(),
+	}
+
+	if withVotes {
+		votes := make([]map[string]interface{}, 0, len(b.votes))
+
+		for _, v := range b.votes {
+			project := s.findProject(v.ProjectID)
+			projectData := map[string]interface{}{"id": v.ProjectID}
+
+			if project != nil {
+				projectData = map[string]interface{}{
+					"id":          project.ID,
+					"name":        project.Name,
+					"desription":  project.Description,
+					"logo_url":    project.LogoURL,
+					"banner_url":  project.BannerURL,
+					"status":      project.Status,
+					"total_votes": project.TotalVotes,
+				}
+			}
+
+			votes = append(votes, map[string]interface{}{
+				"id":           v.ID,
+				"is_confirmed": v.Confirmed,
+				"vote_count":   v.Count,
+				"project":      projectData,
+			})
+		}
+
+		data["votes"] = votes
+	}
+
+	return data
+}
+
+func (b *ballot) findVote(projectID string) *Vote {
+	for _, v := range b.votes {
+		if v.ProjectID == projectID {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func (b *ballot) usedVotes() int64 {
+	var used int64
+
+	for _, v := range b.votes {
+		used += v.Count
+	}
+
+	return used
+}
+
+func verifySignature(address common.Address, message string, signatureHex string) bool {
+	signature, err := hexutil.Decode(signatureHex)
+	if err != nil || len(signature) != 65 {
+		return false
+	}
+
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), signature)
+	if err != nil {
+		return false
+	}
+
+	return crypto.PubkeyToAddress(*publicKey) == address
+}
+
+func writeJSON(
+	w http.ResponseWriter,
+	statusCode int,
+	message string,
+	data interface{},
+	cookies []*http.Cookie,
+) {
+	writeResponse(w, statusCode, map[string]interface{}{
+		"statusCode": statusCode,
+		"message":    message,
+		"data":       data,
+		"metadata":   nil,
+		"error":      nil,
+	}, cookies)
+}
+
+func writeResponse(
+	w http.ResponseWriter,
+	statusCode int,
+	body interface{},
+	cookies []*http.Cookie,
+) {
+	for _, cookie := range cookies {
+		http.SetCookie(w, cookie)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomHex(size int) string {
+	buf := make([]byte, size)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,233 @@
+package runState
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"main/internal/report"
+	"strings"
+	"time"
+)
+
+type Step string
+
+const (
+	StepNone    Step = ""
+	StepAuth    Step = "auth"
+	StepVote    Step = "vote"
+	StepConfirm Step = "confirm"
+	StepVerify  Step = "verify"
+	StepDone    Step = "done"
+)
+
+var stepOrder = map[Step]int{
+	StepNone:    0,
+	StepAuth:    1,
+	StepVote:    2,
+	StepConfirm: 3,
+	StepVerify:  4,
+	StepDone:    5,
+}
+
+var runsBucket = []byte("runs")
+
+type RunInfo struct {
+	ID        string    `json:"id"`
+	Mode      string    `json:"mode"`
+	RoundID   string    `json:"round_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountState is the last completed step of an account, Distribution is saved before the first vote
+// so a resumed run casts the same votes instead of drawing new ones
+type AccountState struct {
+	Step         Step                  `json:"step"`
+	Distribution []report.ProjectVotes `json:"distribution,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+// Passed reports whether the step was completed in an earlier attempt of the run
+func (s AccountState) Passed(step Step) bool {
+	return stepOrder[s.Step] >= stepOrder[step]
+}
+
+var (
+	db  *bolt.DB
+	run RunInfo
+)
+
+func newRunID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+func accountsBucket(runID string) []byte {
+	return []byte("run:" + runID)
+}
+
+// Open starts a new run, or continues resumeID when it is set; mode and round must match the resumed run
+func Open(
+	path string,
+	resumeID string,
+	mode string,
+	roundID string,
+) (RunInfo, error) {
+	var err error
+
+	db, err = bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return RunInfo{}, fmt.Errorf("error when opening run state %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		runs, err := tx.CreateBucketIfNotExists(runsBucket)
+		if err != nil {
+			return err
+		}
+
+		if resumeID == "" {
+			run = RunInfo{ID: newRunID(), Mode: mode, RoundID: roundID, CreatedAt: time.Now()}
+
+			runBytes, err := json.Marshal(run)
+			if err != nil {
+				return err
+			}
+
+			if _, err = tx.CreateBucketIfNotExists(accountsBucket(run.ID)); err != nil {
+				return err
+			}
+
+			return runs.Put([]byte(run.ID), runBytes)
+		}
+
+		runBytes := runs.Get([]byte(resumeID))
+		if runBytes == nil {
+			return fmt.Errorf("run %s not found in %s", resumeID, path)
+		}
+
+		if err := json.Unmarshal(runBytes, &run); err != nil {
+			return err
+		}
+
+		if run.Mode != mode || run.RoundID != roundID {
+			return fmt.Errorf("run %s was started as %s for round %s, not %s for round %s",
+				resumeID, run.Mode, run.RoundID, mode, roundID)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		_ = db.Close()
+		db = nil
+		return RunInfo{}, err
+	}
+
+	return run, nil
+}
+
+func Close() {
+	if db == nil {
+		return
+	}
+
+	if err := db.Close(); err != nil {
+		log.Errorf("Error When Closing Run State: %v", err)
+	}
+
+	db = nil
+}
+
+// Get returns the saved state of the account, or an empty state when no run is open
+func Get(address string) AccountState {
+	var state AccountState
+
+	if db == nil {
+		return state
+	}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		stateBytes := tx.Bucket(accountsBucket(run.ID)).Get([]byte(strings.ToLower(address)))
+		if stateBytes == nil {
+			return nil
+		}
+
+		return json.Unmarshal(stateBytes, &state)
+	})
+
+	if err != nil {
+		log.Errorf("%s | Error When Reading Run State: %v", address, err)
+	}
+
+	return state
+}
+
+func update(address string, change func(state *AccountState)) error {
+	if db == nil {
+		return nil
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(accountsBucket(run.ID))
+		key := []byte(strings.ToLower(address))
+
+		var state AccountState
+		if stateBytes := bucket.Get(key); stateBytes != nil {
+			if err := json.Unmarshal(stateBytes, &state); err != nil {
+				return err
+			}
+		}
+
+		change(&state)
+		state.UpdatedAt = time.Now()
+
+		stateBytes, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(key, stateBytes)
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s | Error When Saving Run State: %v", address, err)
+	}
+
+	return nil
+}
+
+// MarkStep records the step as completed, steps never go backwards; the account must stop when it fails,
+// otherwise a resumed run would not know what was already done
+func MarkStep(address string, step Step) error {
+	return update(address, func(state *AccountState) {
+		if stepOrder[step] > stepOrder[state.Step] {
+			state.Step = step
+		}
+	})
+}
+
+// SaveDistribution must succeed before the first vote, a resumed run would draw a new distribution otherwise
+func SaveDistribution(address string, distribution []report.ProjectVotes) error {
+	return update(address, func(state *AccountState) {
+		state.Distribution = distribution
+	})
+}
+
+// Finish marks the account done on success and keeps its last step on failure for the next resume
+func Finish(address string, err error) error {
+	return update(address, func(state *AccountState) {
+		state.Error = ""
+
+		if err == nil {
+			state.Step = StepDone
+		} else {
+			state.Error = err.Error()
+		}
+	})
+}
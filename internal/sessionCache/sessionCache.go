@@ -0,0 +1,218 @@
+package sessionCache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const formatVersion = 1
+
+// expiryMargin keeps a token that is about to expire from being reused for a whole account flow
+const expiryMargin = time.Minute
+
+// Session is stored encrypted with a key derived from a signature of the account,
+// so the cache is useless without the accounts file, keystore or external signer
+type Session struct {
+	Address          string    `json:"address"`
+	BaseURL          string    `json:"base_url"`
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`  // zero when the API did not tell
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"` // zero when the API did not tell
+	SavedAt          time.Time `json:"saved_at"`
+}
+
+func (s *Session) AccessValid() bool {
+	return s.AccessToken != "" && (s.AccessExpiresAt.IsZero() || time.Now().Add(expiryMargin).Before(s.AccessExpiresAt))
+}
+
+func (s *Session) RefreshValid() bool {
+	return s.RefreshToken != "" && (s.RefreshExpiresAt.IsZero() || time.Now().Add(expiryMargin).Before(s.RefreshExpiresAt))
+}
+
+type cacheFile struct {
+	Version  int               `json:"version"`
+	Sessions map[string]string `json:"sessions"` // entry key -> base64(nonce | AES-GCM ciphertext)
+}
+
+var (
+	mu       sync.Mutex
+	path     string
+	sessions map[string]string
+)
+
+// Init loads the cache file, the cache stays disabled until it is called
+func Init(cachePath string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	file := cacheFile{Sessions: map[string]string{}}
+
+	fileBytes, err := os.ReadFile(cachePath)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("error when reading session cache %s: %v", cachePath, err)
+	default:
+		if err = json.Unmarshal(fileBytes, &file); err != nil || file.Version != formatVersion {
+			log.Warnf("Session Cache %s Is Unreadable Or Outdated, Starting A New One", cachePath)
+			file = cacheFile{Sessions: map[string]string{}}
+		}
+
+		if file.Sessions == nil {
+			file.Sessions = map[string]string{}
+		}
+	}
+
+	path = cachePath
+	sessions = file.Sessions
+
+	return nil
+}
+
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return path != ""
+}
+
+// entryKey hides which addresses are cached, the same address gets separate sessions per API
+func entryKey(address string, baseURL string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(address) + "|" + baseURL))
+	return hex.EncodeToString(hash[:])
+}
+
+func newAEAD(accountKey []byte) (cipher.AEAD, error) {
+	key := sha256.Sum256(append([]byte("retro9000 session cache\x00"), accountKey...))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Load returns the cached session of the address for the API, nil when there is none or it cannot be decrypted
+func Load(
+	accountKey []byte,
+	address string,
+	baseURL string,
+) *Session {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if path == "" || len(accountKey) == 0 {
+		return nil
+	}
+
+	key := entryKey(address, baseURL)
+
+	sealed, err := base64.StdEncoding.DecodeString(sessions[key])
+	if err != nil || len(sealed) == 0 {
+		return nil
+	}
+
+	aead, err := newAEAD(accountKey)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		log.Warnf("%s | Cached Session Cannot Be Decrypted (Was The Key Or The Signer Changed?), Signing In", address)
+		return nil
+	}
+
+	var session Session
+	if err = json.Unmarshal(plaintext, &session); err != nil {
+		return nil
+	}
+
+	return &session
+}
+
+// Save encrypts the session and rewrites the cache file, a failure only costs a sign-in on the next run
+func Save(
+	accountKey []byte,
+	session Session,
+) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if path == "" || len(accountKey) == 0 {
+		return
+	}
+
+	if err := save(accountKey, session); err != nil {
+		log.Warnf("%s | Error When Saving Session Cache: %v", session.Address, err)
+	}
+}
+
+func save(
+	accountKey []byte,
+	session Session,
+) error {
+	key := entryKey(session.Address, session.BaseURL)
+	session.SavedAt = time.Now()
+
+	plaintext, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(accountKey)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sessions[key] = base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, []byte(key)))
+
+	fileBytes, err := json.MarshalIndent(cacheFile{Version: formatVersion, Sessions: sessions}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// written to a temporary file first, so an interrupted run cannot leave a truncated cache
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if err = tempFile.Chmod(0600); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+
+	if _, err = tempFile.Write(fileBytes); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+
+	if err = tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
@@ -0,0 +1,284 @@
+package siwe
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const preambleSuffix = " wants you to sign in with your Ethereum account:"
+
+// Message is an EIP-4361 (Sign-In with Ethereum) message
+type Message struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// Policy is what a sign-in message must satisfy before it is signed, empty lists allow any value.
+// A plain text message has no domain, URI or expiry to check, it is refused unless AllowPlainText is set.
+// Reserved are messages the accounts sign for other purposes, a sign-in message containing one is never signed
+type Policy struct {
+	Domains        []string
+	ChainIDs       []int64
+	MaxAge         time.Duration
+	ClockSkew      time.Duration
+	AllowPlainText bool
+	Reserved       []string
+}
+
+var ErrNotSIWE = errors.New("message is not a Sign-In with Ethereum message")
+
+var (
+	addressPattern = regexp.MustCompile(`0x[0-9a-fA-F]{40}`)
+	// EIP-4361 nonces are at least 8 alphanumeric characters
+	noncePattern = regexp.MustCompile(`^[a-zA-Z0-9]{8,}$`)
+)
+
+// normalizeLines turns CRLF line endings into LF, servers may send either
+func normalizeLines(text string) string {
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
+
+// IsSIWE reports whether the text starts like an EIP-4361 message
+func IsSIWE(text string) bool {
+	firstLine, _, _ := strings.Cut(normalizeLines(text), "\n")
+	return strings.HasSuffix(firstLine, preambleSuffix)
+}
+
+// Parse reads an EIP-4361 message, the fields must come in the order the standard defines
+func Parse(text string) (*Message, error) {
+	lines := strings.Split(normalizeLines(text), "\n")
+
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], preambleSuffix) {
+		return nil, errors.New("missing sign-in preamble")
+	}
+
+	message := &Message{
+		Domain:  strings.TrimSuffix(lines[0], preambleSuffix),
+		Address: strings.TrimSpace(lines[1]),
+	}
+
+	if !common.IsHexAddress(message.Address) {
+		return nil, fmt.Errorf("invalid address %q", message.Address)
+	}
+
+	i := 2
+
+	// an empty line, an optional statement and another empty line separate the address from the fields
+	if i < len(lines) && lines[i] == "" {
+		i++
+	}
+
+	if i < len(lines) && !strings.HasPrefix(lines[i], "URI: ") {
+		message.Statement = lines[i]
+		i++
+
+		if i < len(lines) && lines[i] == "" {
+			i++
+		}
+	}
+
+	fields := map[string]string{}
+	order := []string{"URI", "Version", "Chain ID", "Nonce", "Issued At", "Expiration Time", "Not Before", "Request ID"}
+	next := 0
+
+	for ; i < len(lines); i++ {
+		if lines[i] == "Resources:" {
+			for i++; i < len(lines); i++ {
+				resource, ok := strings.CutPrefix(lines[i], "- ")
+				if !ok {
+					return nil, fmt.Errorf("invalid resource line %q", lines[i])
+				}
+
+				message.Resources = append(message.Resources, resource)
+			}
+
+			break
+		}
+
+		if lines[i] == "" && i == len(lines)-1 {
+			break
+		}
+
+		name, value, ok := strings.Cut(lines[i], ": ")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", lines[i])
+		}
+
+		for next < len(order) && order[next] != name {
+			next++
+		}
+
+		if next == len(order) {
+			return nil, fmt.Errorf("unexpected or repeated field %q", name)
+		}
+
+		fields[name] = value
+		next++
+	}
+
+	for _, name := range []string{"URI", "Version", "Chain ID", "Nonce", "Issued At"} {
+		if fields[name] == "" {
+			return nil, fmt.Errorf("missing field %q", name)
+		}
+	}
+
+	message.URI = fields["URI"]
+	message.Version = fields["Version"]
+	message.Nonce = fields["Nonce"]
+	message.RequestID = fields["Request ID"]
+
+	var err error
+
+	if message.ChainID, err = strconv.ParseInt(fields["Chain ID"], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid chain ID %q", fields["Chain ID"])
+	}
+
+	if message.IssuedAt, err = time.Parse(time.RFC3339, fields["Issued At"]); err != nil {
+		return nil, fmt.Errorf("invalid issued at %q", fields["Issued At"])
+	}
+
+	if message.ExpirationTime, err = parseOptionalTime(fields["Expiration Time"]); err != nil {
+		return nil, fmt.Errorf("invalid expiration time %q", fields["Expiration Time"])
+	}
+
+	if message.NotBefore, err = parseOptionalTime(fields["Not Before"]); err != nil {
+		return nil, fmt.Errorf("invalid not before %q", fields["Not Before"])
+	}
+
+	return message, nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+// Check validates the text that is about to be signed for the address. A SIWE message must be addressed to
+// the address, come from an allowed domain and chain and be fresh; a plain text message is refused unless
+// the policy allows it, and even then it must not mention another address
+func Check(
+	text string,
+	address common.Address,
+	policy Policy,
+	now time.Time,
+) error {
+	for _, reserved := range policy.Reserved {
+		if strings.Contains(text, reserved) {
+			return errors.New("message contains a message reserved for another purpose")
+		}
+	}
+
+	if !IsSIWE(text) {
+		if !policy.AllowPlainText {
+			return ErrNotSIWE
+		}
+
+		for _, mentioned := range addressPattern.FindAllString(text, -1) {
+			if common.HexToAddress(mentioned) != address {
+				return fmt.Errorf("message mentions another address %s", mentioned)
+			}
+		}
+
+		return nil
+	}
+
+	message, err := Parse(text)
+	if err != nil {
+		return fmt.Errorf("malformed Sign-In with Ethereum message: %v", err)
+	}
+
+	return message.check(address, policy, now)
+}
+
+func (m *Message) check(
+	address common.Address,
+	policy Policy,
+	now time.Time,
+) error {
+	if common.HexToAddress(m.Address) != address {
+		return fmt.Errorf("message is addressed to %s", m.Address)
+	}
+
+	if m.Version != "1" {
+		return fmt.Errorf("unsupported message version %q", m.Version)
+	}
+
+	if !noncePattern.MatchString(m.Nonce) {
+		return fmt.Errorf("invalid nonce %q", m.Nonce)
+	}
+
+	if len(policy.Domains) > 0 && !containsFold(policy.Domains, m.Domain) {
+		return fmt.Errorf("domain %s is not one of %s", m.Domain, strings.Join(policy.Domains, ", "))
+	}
+
+	uri, err := url.Parse(m.URI)
+	if err != nil || !strings.EqualFold(uri.Host, m.Domain) {
+		return fmt.Errorf("URI %s does not belong to domain %s", m.URI, m.Domain)
+	}
+
+	if len(policy.ChainIDs) > 0 && !containsChain(policy.ChainIDs, m.ChainID) {
+		return fmt.Errorf("chain ID %d is not expected", m.ChainID)
+	}
+
+	if m.IssuedAt.After(now.Add(policy.ClockSkew)) {
+		return fmt.Errorf("message is issued in the future (%s)", m.IssuedAt.Format(time.RFC3339))
+	}
+
+	if policy.MaxAge > 0 && now.Sub(m.IssuedAt) > policy.MaxAge+policy.ClockSkew {
+		return fmt.Errorf("message was issued %s ago", now.Sub(m.IssuedAt).Round(time.Second))
+	}
+
+	if m.ExpirationTime != nil && !now.Before(m.ExpirationTime.Add(policy.ClockSkew)) {
+		return fmt.Errorf("message expired at %s", m.ExpirationTime.Format(time.RFC3339))
+	}
+
+	if m.NotBefore != nil && now.Add(policy.ClockSkew).Before(*m.NotBefore) {
+		return fmt.Errorf("message is not valid before %s", m.NotBefore.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsChain(chainIDs []int64, chainID int64) bool {
+	for _, item := range chainIDs {
+		if item == chainID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,186 @@
+package siwe
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testAddress  = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	otherAddress = common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	testNow      = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+)
+
+var testPolicy = Policy{
+	Domains:   []string{"retro9000.avax.network"},
+	ChainIDs:  []int64{43114},
+	MaxAge:    10 * time.Minute,
+	ClockSkew: time.Minute,
+}
+
+// testMessage builds a message with the fields of the overrides replaced, an empty value drops the line
+func testMessage(overrides map[string]string) string {
+	fields := []struct{ name, value string }{
+		{"preamble", "retro9000.avax.network wants you to sign in with your Ethereum account:"},
+		{"address", testAddress.Hex()},
+		{"", ""},
+		{"statement", "Sign in to Retro9000."},
+		{"", ""},
+		{"URI", "https://retro9000.avax.network"},
+		{"Version", "1"},
+		{"Chain ID", "43114"},
+		{"Nonce", "a1b2c3d4e5f6"},
+		{"Issued At", "2025-03-01T11:58:00Z"},
+		{"Expiration Time", ""},
+	}
+
+	var lines []string
+
+	for _, field := range fields {
+		value, ok := overrides[field.name]
+		if !ok {
+			value = field.value
+		}
+
+		switch {
+		case field.name == "":
+			lines = append(lines, "")
+		case value == "":
+		case field.name == "preamble" || field.name == "address" || field.name == "statement":
+			lines = append(lines, value)
+		default:
+			lines = append(lines, field.name+": "+value)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestParse(t *testing.T) {
+	text := testMessage(map[string]string{"Expiration Time": "2025-03-01T12:10:00Z"}) +
+		"\nRequest ID: 42\nResources:\n- https://retro9000.avax.network/terms"
+
+	message, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Domain != "retro9000.avax.network" || message.Address != testAddress.Hex() ||
+		message.Statement != "Sign in to Retro9000." || message.URI != "https://retro9000.avax.network" ||
+		message.ChainID != 43114 || message.Nonce != "a1b2c3d4e5f6" || message.RequestID != "42" {
+		t.Errorf("unexpected message %+v", message)
+	}
+
+	if message.ExpirationTime == nil || !message.ExpirationTime.Equal(time.Date(2025, 3, 1, 12, 10, 0, 0, time.UTC)) {
+		t.Errorf("expiration time is %v", message.ExpirationTime)
+	}
+
+	if len(message.Resources) != 1 || message.Resources[0] != "https://retro9000.avax.network/terms" {
+		t.Errorf("resources are %v", message.Resources)
+	}
+}
+
+func TestParseRejectsMalformedMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+	}{
+		{"missing nonce", map[string]string{"Nonce": ""}},
+		{"invalid address", map[string]string{"address": "0x1234"}},
+		{"invalid chain ID", map[string]string{"Chain ID": "avalanche"}},
+		{"invalid issued at", map[string]string{"Issued At": "yesterday"}},
+		{"invalid expiration time", map[string]string{"Expiration Time": "soon"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(testMessage(test.overrides)); err == nil {
+				t.Error("Parse accepted a malformed message")
+			}
+		})
+	}
+}
+
+func TestIsSIWEAcceptsCRLF(t *testing.T) {
+	text := strings.ReplaceAll(testMessage(nil), "\n", "\r\n")
+
+	if !IsSIWE(text) {
+		t.Fatal("a CRLF message is not recognized as Sign-In with Ethereum")
+	}
+
+	if err := Check(text, testAddress, testPolicy, testNow); err != nil {
+		t.Errorf("Check refused a valid CRLF message: %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		address   common.Address
+		wantErr   bool
+	}{
+		{"valid", nil, testAddress, false},
+		{"another address", nil, otherAddress, true},
+		{"unexpected domain", map[string]string{
+			"preamble": "retro9000.example.com wants you to sign in with your Ethereum account:",
+			"URI":      "https://retro9000.example.com",
+		}, testAddress, true},
+		{"URI of another domain", map[string]string{"URI": "https://evil.example.com"}, testAddress, true},
+		{"unexpected chain ID", map[string]string{"Chain ID": "1"}, testAddress, true},
+		{"unsupported version", map[string]string{"Version": "2"}, testAddress, true},
+		{"short nonce", map[string]string{"Nonce": "abc123"}, testAddress, true},
+		{"nonce with symbols", map[string]string{"Nonce": "abc-123-def"}, testAddress, true},
+		{"issued in the future", map[string]string{"Issued At": "2025-03-01T12:05:00Z"}, testAddress, true},
+		{"issued within clock skew", map[string]string{"Issued At": "2025-03-01T12:00:30Z"}, testAddress, false},
+		{"too old", map[string]string{"Issued At": "2025-03-01T11:30:00Z"}, testAddress, true},
+		{"expired", map[string]string{"Expiration Time": "2025-03-01T11:58:30Z"}, testAddress, true},
+		{"not expired yet", map[string]string{"Expiration Time": "2025-03-01T12:10:00Z"}, testAddress, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Check(testMessage(test.overrides), test.address, testPolicy, testNow)
+
+			if (err != nil) != test.wantErr {
+				t.Errorf("Check returned %v, want error: %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPlainText(t *testing.T) {
+	text := "Sign in to Retro9000 with " + testAddress.Hex() + ", nonce a1b2c3d4"
+
+	if err := Check(text, testAddress, testPolicy, testNow); !errors.Is(err, ErrNotSIWE) {
+		t.Errorf("Check returned %v for plain text, want ErrNotSIWE", err)
+	}
+
+	allowed := testPolicy
+	allowed.AllowPlainText = true
+
+	if err := Check(text, testAddress, allowed, testNow); err != nil {
+		t.Errorf("Check refused allowed plain text: %v", err)
+	}
+
+	if err := Check(text, otherAddress, allowed, testNow); err == nil {
+		t.Error("Check accepted plain text that mentions another address")
+	}
+}
+
+func TestCheckRefusesReservedMessages(t *testing.T) {
+	policy := testPolicy
+	policy.AllowPlainText = true
+	policy.Reserved = []string{"Unlock the session cache"}
+
+	if err := Check("Unlock the session cache", testAddress, policy, testNow); err == nil {
+		t.Error("Check accepted a reserved message")
+	}
+
+	if err := Check(testMessage(map[string]string{"statement": "Unlock the session cache"}), testAddress, policy, testNow); err == nil {
+		t.Error("Check accepted a sign-in message that contains a reserved message")
+	}
+}
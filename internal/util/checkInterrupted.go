@@ -0,0 +1,22 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/pkg/types"
+)
+
+var ErrInterrupted = errors.New("interrupted")
+
+func CheckInterrupted(
+	ctx context.Context,
+	accountData types.AccountData,
+	nextStep string,
+) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s | Stopped Before %s: %w", accountData.AccountAddress.String(), nextStep, ErrInterrupted)
+	}
+
+	return nil
+}
@@ -1,14 +1,121 @@
 package util
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/fasthttpproxy"
+	"main/pkg/types"
+	"net"
 	"net/url"
+	"os"
+	"strings"
 	"time"
 )
 
+var ErrPinMismatch = errors.New("certificate does not match any SPKI pin")
+
+var tlsSettings struct {
+	rootCAs  *x509.CertPool // nil - system roots
+	insecure bool
+	pinHost  string
+	pins     map[string]bool // base64 SHA-256 of the SubjectPublicKeyInfo
+}
+
+// InitTLS sets up certificate verification for every client: an extra CA bundle added to the system
+// roots and SPKI pins for the API host. Pins are checked even when verification is disabled
+func InitTLS(
+	settings types.TLSSettings,
+	apiBaseURL string,
+	insecure bool,
+) error {
+	tlsSettings.insecure = insecure
+	tlsSettings.rootCAs = nil
+	tlsSettings.pins = nil
+
+	if settings.CAFile != "" {
+		caBytes, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return fmt.Errorf("error when reading CA bundle: %v", err)
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM(caBytes) {
+			return fmt.Errorf("no PEM certificates in CA bundle %s", settings.CAFile)
+		}
+
+		tlsSettings.rootCAs = rootCAs
+	}
+
+	if len(settings.SPKIPins) > 0 {
+		apiURL, err := url.Parse(apiBaseURL)
+		if err != nil || apiURL.Hostname() == "" {
+			return fmt.Errorf("cannot pin the API host of %q", apiBaseURL)
+		}
+
+		tlsSettings.pinHost = apiURL.Hostname()
+		tlsSettings.pins = map[string]bool{}
+
+		for _, pin := range settings.SPKIPins {
+			pin = strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+
+			if decoded, err := base64.StdEncoding.DecodeString(pin); err != nil || len(decoded) != sha256.Size {
+				return fmt.Errorf("invalid SPKI pin %q, expected base64 of a SHA-256 hash", pin)
+			}
+
+			tlsSettings.pins[pin] = true
+		}
+	}
+
+	return nil
+}
+
+// IsCertificateError reports whether the request failed because the server certificate was not trusted,
+// retrying through the same connection path cannot change that
+func IsCertificateError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	return errors.Is(err, ErrPinMismatch) || errors.As(err, &verificationErr) ||
+		errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// verifyPins accepts the connection when any certificate of the chain has a pinned public key
+func verifyPins(state tls.ConnectionState) error {
+	if len(tlsSettings.pins) == 0 {
+		return nil
+	}
+
+	// no server name is sent for an IP address, so an IP API host is matched by the missing name
+	pinnedHost := strings.EqualFold(state.ServerName, tlsSettings.pinHost) ||
+		(state.ServerName == "" && net.ParseIP(tlsSettings.pinHost) != nil)
+
+	if !pinnedHost {
+		return nil
+	}
+
+	for _, cert := range state.PeerCertificates {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+		if tlsSettings.pins[base64.StdEncoding.EncodeToString(hash[:])] {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w", tlsSettings.pinHost, ErrPinMismatch)
+}
+
 func GetClient(proxy string) *fasthttp.Client {
 	var dial fasthttp.DialFunc
 
@@ -54,7 +161,9 @@ func GetClient(proxy string) *fasthttp.Client {
 
 		Renegotiation:          tls.RenegotiateNever,
 		SessionTicketsDisabled: false,
-		InsecureSkipVerify:     true,
+		RootCAs:                tlsSettings.rootCAs,
+		InsecureSkipVerify:     tlsSettings.insecure,
+		VerifyConnection:       verifyPins,
 	}
 
 	client := &fasthttp.Client{
@@ -0,0 +1,504 @@
+package votePlan
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"main/internal/projectFilter"
+	"main/internal/retroActions"
+	"main/internal/util"
+	"main/pkg/types"
+	util2 "main/pkg/util"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Entry is one plan row: account (address or label), project (ID or name) and votes ("10" or "25%")
+type Entry struct {
+	Account string `yaml:"account"`
+	Project string `yaml:"project"`
+	Votes   string `yaml:"votes"`
+	Line    int    `yaml:"-"`
+}
+
+type Allocation struct {
+	ProjectID   string
+	ProjectName string
+	Votes       int64
+}
+
+type Plan struct {
+	Path    string
+	Entries []Entry
+}
+
+// Load reads a plan from a .yaml/.yml (list of entries) or .csv (account,project,votes) file
+func Load(path string) (*Plan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error when opening plan: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		entries, err = readYAML(file)
+	case ".csv":
+		entries, err = readCSV(file)
+	default:
+		return nil, fmt.Errorf("unsupported plan format %q, use .yaml, .yml or .csv", filepath.Ext(path))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error when reading plan %s: %v", path, err)
+	}
+
+	plan := &Plan{Path: path, Entries: entries}
+
+	if err = plan.check(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func readYAML(reader io.Reader) ([]Entry, error) {
+	var root yaml.Node
+
+	if err := yaml.NewDecoder(reader).Decode(&root); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.SequenceNode {
+		return nil, errors.New("plan must be a list of {account, project, votes} entries")
+	}
+
+	entries := make([]Entry, 0, len(root.Content[0].Content))
+
+	for _, node := range root.Content[0].Content {
+		var entry Entry
+
+		if err := node.Decode(&entry); err != nil {
+			return nil, err
+		}
+
+		entry.Line = node.Line
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func readCSV(reader io.Reader) ([]Entry, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comment = '#'
+	csvReader.TrimLeadingSpace = true
+
+	var entries []Entry
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := csvReader.FieldPos(0)
+
+		if len(record) != 3 {
+			return nil, fmt.Errorf("line %d: expected 3 columns (account,project,votes), got %d", line, len(record))
+		}
+
+		if len(entries) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "account") {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			Account: strings.TrimSpace(record[0]),
+			Project: strings.TrimSpace(record[1]),
+			Votes:   strings.TrimSpace(record[2]),
+			Line:    line,
+		})
+	}
+
+	return entries, nil
+}
+
+// AvailableVotes returns how many votes the plan may give the account with this ballot
+type AvailableVotes func(votesData *retroActions.GetVotesResponse) int64
+
+// checkThreads caps how many ballots are read at the same time while the plan is checked
+const checkThreads = 10
+
+// Prepare loads the plan and checks it before any account starts: the projects against the shared
+// projects list, fetched with the first account, and, when availableVotes is set, the vote counts
+// of every account against its ballot
+func Prepare(
+	ctx context.Context,
+	path string,
+	accountsList []types.AccountData,
+	filter *projectFilter.Filter,
+	availableVotes AvailableVotes,
+) (*Plan, error) {
+	plan, err := Load(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = plan.CheckAccounts(accountsList); err != nil {
+		return nil, err
+	}
+
+	if len(accountsList) == 0 {
+		return plan, nil
+	}
+
+	projectsList, err := retroActions.FetchSharedProjectsList(ctx,
+		util.GetClient(util2.ProxiesCycler.Next()), accountsList[0])
+
+	if err != nil {
+		return nil, fmt.Errorf("error when fetching projects to check the plan: %v", err)
+	}
+
+	if err = plan.CheckProjects(accountsList, projectsList, filter); err != nil {
+		return nil, err
+	}
+
+	if availableVotes == nil {
+		return plan, nil
+	}
+
+	if err = plan.CheckVotes(ctx, accountsList, projectsList, filter, availableVotes); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// parseVotes returns either an absolute count or a percentage of the available votes
+func parseVotes(value string) (int64, float64, error) {
+	if percentValue, isPercent := strings.CutSuffix(value, "%"); isPercent {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(percentValue), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return 0, 0, fmt.Errorf("invalid percentage %q", value)
+		}
+
+		return 0, percent, nil
+	}
+
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("invalid vote count %q", value)
+	}
+
+	return count, 0, nil
+}
+
+// check validates what can be validated without the projects list and the ballots
+func (p *Plan) check() error {
+	var problems []string
+	percentByAccount := map[string]float64{}
+
+	for _, entry := range p.Entries {
+		if entry.Account == "" || entry.Project == "" {
+			problems = append(problems, fmt.Sprintf("line %d: account and project are required", entry.Line))
+			continue
+		}
+
+		_, percent, err := parseVotes(entry.Votes)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", entry.Line, err))
+			continue
+		}
+
+		accountKey := strings.ToLower(entry.Account)
+		percentByAccount[accountKey] += percent
+
+		if percentByAccount[accountKey] > 100 {
+			problems = append(problems, fmt.Sprintf("line %d: percentages of %s exceed 100%%", entry.Line, entry.Account))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid plan %s:\n  %s", p.Path, strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// CheckAccounts rejects plan entries that match none of the loaded accounts
+func (p *Plan) CheckAccounts(accountsList []types.AccountData) error {
+	var problems []string
+
+	for _, entry := range p.Entries {
+		matched := false
+
+		for _, accountData := range accountsList {
+			if entryMatches(entry, accountData) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			problems = append(problems, fmt.Sprintf("line %d: unknown account %s", entry.Line, entry.Account))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid plan %s:\n  %s", p.Path, strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func entryMatches(entry Entry, accountData types.AccountData) bool {
+	return strings.EqualFold(entry.Account, accountData.AccountAddress.String()) ||
+		(accountData.Label != "" && entry.Account == accountData.Label)
+}
+
+// ForAccount returns the entries addressed to the account by its address or label
+func (p *Plan) ForAccount(accountData types.AccountData) []Entry {
+	var entries []Entry
+
+	for _, entry := range p.Entries {
+		if entryMatches(entry, accountData) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func findProject(projects []retroActions.ProjectData, reference string) (*retroActions.ProjectData, error) {
+	for i := range projects {
+		if projects[i].ID == reference {
+			return &projects[i], nil
+		}
+	}
+
+	var found *retroActions.ProjectData
+
+	for i := range projects {
+		if !strings.EqualFold(strings.TrimSpace(projects[i].Name), reference) {
+			continue
+		}
+
+		if found != nil {
+			return nil, fmt.Errorf("project name %q is ambiguous, use the project ID", reference)
+		}
+
+		found = &projects[i]
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("unknown project %q", reference)
+	}
+
+	return found, nil
+}
+
+// resolveProject finds the entry project and rejects deleted projects and projects the filter does not pass
+func resolveProject(
+	entry Entry,
+	projects []retroActions.ProjectData,
+	filter *projectFilter.Filter,
+) (*retroActions.ProjectData, error) {
+	project, err := findProject(projects, entry.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.IsDeleted {
+		return nil, fmt.Errorf("project %q is deleted", entry.Project)
+	}
+
+	if filter != nil && !filter.Match(*project) {
+		return nil, fmt.Errorf("project %q is excluded by project_filter", entry.Project)
+	}
+
+	return project, nil
+}
+
+// CheckProjects validates the projects of every account entry against the projects list, so a wrong
+// line stops the run before any account votes. Vote counts depend on the ballots and are checked by CheckVotes
+func (p *Plan) CheckProjects(
+	accountsList []types.AccountData,
+	projects []retroActions.ProjectData,
+	filter *projectFilter.Filter,
+) error {
+	var problems []string
+	reportedLines := map[int]bool{}
+
+	for _, accountData := range accountsList {
+		seenProjects := map[string]int{}
+
+		for _, entry := range p.ForAccount(accountData) {
+			project, err := resolveProject(entry, projects, filter)
+
+			if err != nil {
+				if !reportedLines[entry.Line] {
+					reportedLines[entry.Line] = true
+					problems = append(problems, fmt.Sprintf("line %d: %v", entry.Line, err))
+				}
+
+				continue
+			}
+
+			if previousLine, ok := seenProjects[project.ID]; ok {
+				if !reportedLines[entry.Line] {
+					reportedLines[entry.Line] = true
+					problems = append(problems, fmt.Sprintf("line %d: project %q is already planned for %s on line %d",
+						entry.Line, entry.Project, accountData.AccountAddress.String(), previousLine))
+				}
+
+				continue
+			}
+
+			seenProjects[project.ID] = entry.Line
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid plan %s:\n  %s", p.Path, strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// CheckVotes reads the ballot of every planned account and resolves its entries, so over-allocation
+// of any account is reported before the first vote of the run
+func (p *Plan) CheckVotes(
+	ctx context.Context,
+	accountsList []types.AccountData,
+	projects []retroActions.ProjectData,
+	filter *projectFilter.Filter,
+	availableVotes AvailableVotes,
+) error {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, checkThreads)
+	accountProblems := make([]string, len(accountsList))
+
+	for i, accountData := range accountsList {
+		entries := p.ForAccount(accountData)
+
+		if len(entries) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, accountData types.AccountData, entries []Entry) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			votesData, err := retroActions.FetchVotes(ctx, util.GetClient(util2.ProxiesCycler.Next()), accountData)
+
+			if err != nil {
+				accountProblems[i] = fmt.Sprintf("error when reading the ballot: %v", err)
+				return
+			}
+
+			if _, err = Resolve(entries, projects, filter, availableVotes(votesData)); err != nil {
+				accountProblems[i] = fmt.Sprintf("%s: %v", accountData.AccountAddress.String(), err)
+			}
+		}(i, accountData, entries)
+	}
+
+	wg.Wait()
+
+	var problems []string
+
+	for _, problem := range accountProblems {
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid plan %s:\n  %s", p.Path, strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// Resolve turns the account entries into vote counts and rejects unknown projects, projects
+// the filter does not pass and over-allocation
+func Resolve(
+	entries []Entry,
+	projects []retroActions.ProjectData,
+	filter *projectFilter.Filter,
+	availableVotes int64,
+) ([]Allocation, error) {
+	var problems []string
+	var allocations []Allocation
+	var totalVotes int64
+	seenProjects := map[string]int{}
+
+	for _, entry := range entries {
+		project, err := resolveProject(entry, projects, filter)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", entry.Line, err))
+			continue
+		}
+
+		if previousLine, ok := seenProjects[project.ID]; ok {
+			problems = append(problems, fmt.Sprintf("line %d: project %q is already planned on line %d",
+				entry.Line, entry.Project, previousLine))
+			continue
+		}
+
+		seenProjects[project.ID] = entry.Line
+
+		votes, percent, err := parseVotes(entry.Votes)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", entry.Line, err))
+			continue
+		}
+
+		if percent > 0 {
+			votes = int64(float64(availableVotes) * percent / 100)
+
+			if votes < 1 {
+				problems = append(problems, fmt.Sprintf("line %d: %s of %d available votes is less than 1 vote",
+					entry.Line, entry.Votes, availableVotes))
+				continue
+			}
+		}
+
+		totalVotes += votes
+		allocations = append(allocations, Allocation{
+			ProjectID:   project.ID,
+			ProjectName: project.Name,
+			Votes:       votes,
+		})
+	}
+
+	if totalVotes > availableVotes {
+		problems = append(problems, fmt.Sprintf("plan allocates %d votes, but only %d are available",
+			totalVotes, availableVotes))
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+
+	return allocations, nil
+}
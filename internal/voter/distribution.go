@@ -0,0 +1,266 @@
+package voter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"main/internal/retroActions"
+	"main/pkg/types"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	StrategyRandom   = "random"
+	StrategyEqual    = "equal"
+	StrategyWeighted = "weighted"
+)
+
+// MaxWeight bounds a single weight, so the sum of weights cannot overflow int64
+const MaxWeight = 1_000_000_000
+
+// DistributionStrategy splits totalVotes between projects; the result always sums to totalVotes
+// and every allocation is at least 1
+type DistributionStrategy interface {
+	Distribute(projects []retroActions.ProjectData, totalVotes int64) ([]DistributionData, error)
+}
+
+// RandomStrategy votes for a random number of random projects with random amounts
+type RandomStrategy struct {
+	Rng         *rand.Rand
+	MinProjects int
+	MaxProjects int
+}
+
+// EqualStrategy votes for a random number of random projects with equal amounts
+type EqualStrategy struct {
+	Rng         *rand.Rand
+	MinProjects int
+	MaxProjects int
+}
+
+// WeightedStrategy votes only for configured projects, proportionally to their weights
+type WeightedStrategy struct {
+	Weights map[string]int64 // project ID or name -> weight
+}
+
+func NewStrategy(
+	settings types.DistributionSettings,
+	rng *rand.Rand,
+) (DistributionStrategy, error) {
+	switch strings.ToLower(settings.Strategy) {
+	case "", StrategyRandom:
+		return &RandomStrategy{Rng: rng, MinProjects: settings.MinProjects, MaxProjects: settings.MaxProjects}, nil
+	case StrategyEqual:
+		return &EqualStrategy{Rng: rng, MinProjects: settings.MinProjects, MaxProjects: settings.MaxProjects}, nil
+	case StrategyWeighted:
+		if len(settings.Weights) == 0 {
+			return nil, fmt.Errorf("distribution strategy %q requires weights", StrategyWeighted)
+		}
+
+		for key, weight := range settings.Weights {
+			if weight <= 0 || weight > MaxWeight {
+				return nil, fmt.Errorf("weight of %q must be between 1 and %d", key, MaxWeight)
+			}
+		}
+
+		return &WeightedStrategy{Weights: settings.Weights}, nil
+	default:
+		return nil, fmt.Errorf("unknown distribution strategy: %s", settings.Strategy)
+	}
+}
+
+// AccountSeed derives a per-account seed from the run seed, so one logged seed reproduces every account
+func AccountSeed(runSeed int64, address string) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(strings.ToLower(address)))
+
+	return runSeed ^ int64(hash.Sum64())
+}
+
+// pickProjects selects between minProjects and maxProjects random projects, never more than totalVotes
+func pickProjects(
+	rng *rand.Rand,
+	projects []retroActions.ProjectData,
+	totalVotes int64,
+	minProjects int,
+	maxProjects int,
+) []retroActions.ProjectData {
+	if minProjects < 1 {
+		minProjects = 1
+	}
+
+	if maxProjects < minProjects {
+		maxProjects = minProjects
+	}
+
+	numProjects := minProjects + rng.Intn(maxProjects-minProjects+1)
+
+	if numProjects > len(projects) {
+		numProjects = len(projects)
+	}
+
+	if int64(numProjects) > totalVotes {
+		numProjects = int(totalVotes)
+	}
+
+	selectedProjects := make([]retroActions.ProjectData, 0, numProjects)
+	for _, projectIndex := range rng.Perm(len(projects))[:numProjects] {
+		selectedProjects = append(selectedProjects, projects[projectIndex])
+	}
+
+	return selectedProjects
+}
+
+func (s *RandomStrategy) Distribute(
+	projects []retroActions.ProjectData,
+	totalVotes int64,
+) ([]DistributionData, error) {
+	if len(projects) == 0 || totalVotes <= 0 {
+		return nil, nil
+	}
+
+	selectedProjects := pickProjects(s.Rng, projects, totalVotes, s.MinProjects, s.MaxProjects)
+	numProjects := len(selectedProjects)
+	distribution := make([]DistributionData, 0, numProjects)
+	remainingVotes := totalVotes
+
+	for i, project := range selectedProjects {
+		var votes int64
+		if i == numProjects-1 {
+			// Последний проект получает все оставшиеся голоса
+			votes = remainingVotes
+		} else {
+			// Не больше среднего остатка, чтобы каждому следующему проекту хватило хотя бы 1 голоса
+			maxVotes := remainingVotes / int64(numProjects-i)
+			votes = 1
+			if maxVotes > 1 {
+				votes = s.Rng.Int63n(maxVotes) + 1
+			}
+		}
+
+		remainingVotes -= votes
+
+		distribution = append(distribution, DistributionData{
+			ProjectID:   project.ID,
+			VotesAmount: votes,
+		})
+	}
+
+	return distribution, nil
+}
+
+func (s *EqualStrategy) Distribute(
+	projects []retroActions.ProjectData,
+	totalVotes int64,
+) ([]DistributionData, error) {
+	if len(projects) == 0 || totalVotes <= 0 {
+		return nil, nil
+	}
+
+	selectedProjects := pickProjects(s.Rng, projects, totalVotes, s.MinProjects, s.MaxProjects)
+	numProjects := int64(len(selectedProjects))
+	distribution := make([]DistributionData, 0, numProjects)
+
+	for i, project := range selectedProjects {
+		votes := totalVotes / numProjects
+		// Остаток от деления раздаем первым проектам по одному голосу
+		if int64(i) < totalVotes%numProjects {
+			votes++
+		}
+
+		distribution = append(distribution, DistributionData{
+			ProjectID:   project.ID,
+			VotesAmount: votes,
+		})
+	}
+
+	return distribution, nil
+}
+
+func (s *WeightedStrategy) Distribute(
+	projects []retroActions.ProjectData,
+	totalVotes int64,
+) ([]DistributionData, error) {
+	if totalVotes <= 0 {
+		return nil, nil
+	}
+
+	type weightedProject struct {
+		projectID string
+		weight    int64
+	}
+
+	var weighted []weightedProject
+	var totalWeight int64
+
+	for _, project := range projects {
+		weight, ok := s.Weights[project.ID]
+		if !ok {
+			weight, ok = s.Weights[project.Name]
+		}
+
+		if !ok || project.IsDeleted {
+			continue
+		}
+
+		weighted = append(weighted, weightedProject{project.ID, weight})
+		totalWeight += weight
+	}
+
+	if len(weighted) == 0 {
+		return nil, fmt.Errorf("none of the weighted projects were found in the projects list")
+	}
+
+	// Самые весомые проекты первыми получают голоса, если голосов меньше, чем проектов
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].weight > weighted[j].weight
+	})
+
+	if int64(len(weighted)) > totalVotes {
+		weighted = weighted[:totalVotes]
+		totalWeight = 0
+		for _, project := range weighted {
+			totalWeight += project.weight
+		}
+	}
+
+	// Каждый проект получает 1 голос, остальное делится пропорционально весам методом наибольшего остатка
+	spareVotes := totalVotes - int64(len(weighted))
+	distribution := make([]DistributionData, len(weighted))
+	remainders := make([]int64, len(weighted))
+	var allocatedVotes int64
+
+	// spareVotes * weight may not fit int64, the share and the remainder always do
+	bigTotalWeight := big.NewInt(totalWeight)
+
+	for i, project := range weighted {
+		product := new(big.Int).Mul(big.NewInt(spareVotes), big.NewInt(project.weight))
+		bigShare, bigRemainder := product.QuoRem(product, bigTotalWeight, new(big.Int))
+
+		share := bigShare.Int64()
+		remainders[i] = bigRemainder.Int64()
+		allocatedVotes += share
+
+		distribution[i] = DistributionData{
+			ProjectID:   project.projectID,
+			VotesAmount: share + 1,
+		}
+	}
+
+	order := make([]int, len(weighted))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+
+	for i := int64(0); i < spareVotes-allocatedVotes; i++ {
+		distribution[order[i]].VotesAmount++
+	}
+
+	return distribution, nil
+}
@@ -0,0 +1,242 @@
+package voter
+
+import (
+	"fmt"
+	"main/internal/retroActions"
+	"main/pkg/types"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func testProjects(count int) []retroActions.ProjectData {
+	projects := make([]retroActions.ProjectData, 0, count)
+
+	for i := 1; i <= count; i++ {
+		projects = append(projects, retroActions.ProjectData{
+			ID:   fmt.Sprintf("project-%d", i),
+			Name: fmt.Sprintf("Project %d", i),
+		})
+	}
+
+	return projects
+}
+
+func checkDistribution(t *testing.T, distribution []DistributionData, totalVotes int64) {
+	t.Helper()
+
+	var sum int64
+	seen := map[string]bool{}
+
+	for _, data := range distribution {
+		if data.VotesAmount < 1 {
+			t.Errorf("%s got %d votes, every share must be at least 1", data.ProjectID, data.VotesAmount)
+		}
+
+		if seen[data.ProjectID] {
+			t.Errorf("%s is allocated twice", data.ProjectID)
+		}
+
+		seen[data.ProjectID] = true
+		sum += data.VotesAmount
+	}
+
+	if sum != totalVotes {
+		t.Errorf("shares sum to %d, want %d", sum, totalVotes)
+	}
+}
+
+func TestStrategiesAllocateEveryVote(t *testing.T) {
+	weights := map[string]int64{}
+	for i := 1; i <= 10; i++ {
+		weights[fmt.Sprintf("project-%d", i)] = int64(i)
+	}
+
+	tests := []struct {
+		name        string
+		strategy    string
+		projects    int
+		totalVotes  int64
+		minProjects int
+		maxProjects int
+	}{
+		{"random", StrategyRandom, 20, 100, 3, 10},
+		{"random fewer votes than projects", StrategyRandom, 20, 4, 5, 10},
+		{"random single project", StrategyRandom, 1, 57, 1, 5},
+		{"random one vote", StrategyRandom, 20, 1, 1, 20},
+		{"equal", StrategyEqual, 20, 100, 3, 10},
+		{"equal with remainder", StrategyEqual, 20, 101, 7, 7},
+		{"equal fewer votes than projects", StrategyEqual, 20, 3, 5, 10},
+		{"weighted", StrategyWeighted, 20, 100, 0, 0},
+		{"weighted fewer votes than projects", StrategyWeighted, 20, 4, 0, 0},
+		{"weighted one vote per project", StrategyWeighted, 20, 10, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := types.DistributionSettings{
+				Strategy:    test.strategy,
+				MinProjects: test.minProjects,
+				MaxProjects: test.maxProjects,
+				Weights:     weights,
+			}
+
+			for seed := int64(1); seed <= 50; seed++ {
+				strategy, err := NewStrategy(settings, rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				distribution, err := strategy.Distribute(testProjects(test.projects), test.totalVotes)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				checkDistribution(t, distribution, test.totalVotes)
+			}
+		})
+	}
+}
+
+func TestStrategiesAreDeterministicForSeed(t *testing.T) {
+	for _, strategyName := range []string{StrategyRandom, StrategyEqual} {
+		t.Run(strategyName, func(t *testing.T) {
+			settings := types.DistributionSettings{Strategy: strategyName, MinProjects: 2, MaxProjects: 8}
+			seed := AccountSeed(42, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+
+			distribute := func(seed int64) []DistributionData {
+				strategy, err := NewStrategy(settings, rand.New(rand.NewSource(seed)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				distribution, err := strategy.Distribute(testProjects(30), 1000)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return distribution
+			}
+
+			if first, second := distribute(seed), distribute(seed); !reflect.DeepEqual(first, second) {
+				t.Errorf("same seed gave %v and %v", first, second)
+			}
+
+			if first, other := distribute(seed), distribute(seed+1); reflect.DeepEqual(first, other) {
+				t.Errorf("different seeds gave the same distribution %v", first)
+			}
+		})
+	}
+}
+
+func TestAccountSeedIgnoresAddressCase(t *testing.T) {
+	address := "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+
+	if AccountSeed(7, address) != AccountSeed(7, "0xF39FD6E51AAD88F6F4CE6AB8827279CFFFB92266") {
+		t.Error("account seed depends on the address case")
+	}
+
+	if AccountSeed(7, address) == AccountSeed(8, address) {
+		t.Error("account seed does not depend on the run seed")
+	}
+}
+
+func TestWeightedStrategySplitsByWeight(t *testing.T) {
+	tests := []struct {
+		name       string
+		weights    map[string]int64
+		totalVotes int64
+		want       map[string]int64
+	}{
+		{
+			name:       "proportional",
+			weights:    map[string]int64{"project-1": 3, "Project 2": 1},
+			totalVotes: 102,
+			want:       map[string]int64{"project-1": 76, "project-2": 26},
+		},
+		{
+			name:       "largest remainder",
+			weights:    map[string]int64{"project-1": 1, "project-2": 1, "project-3": 1},
+			totalVotes: 10,
+			want:       map[string]int64{"project-1": 4, "project-2": 3, "project-3": 3},
+		},
+		{
+			name:       "heaviest projects first when votes are short",
+			weights:    map[string]int64{"project-1": 1, "project-2": 5, "project-3": 2},
+			totalVotes: 2,
+			want:       map[string]int64{"project-2": 1, "project-3": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			strategy, err := NewStrategy(types.DistributionSettings{Strategy: StrategyWeighted, Weights: test.weights}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			distribution, err := strategy.Distribute(testProjects(5), test.totalVotes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkDistribution(t, distribution, test.totalVotes)
+
+			got := map[string]int64{}
+			for _, data := range distribution {
+				got[data.ProjectID] = data.VotesAmount
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWeightedStrategyDoesNotOverflow(t *testing.T) {
+	weights := map[string]int64{"project-1": MaxWeight, "project-2": 1}
+	totalVotes := int64(math.MaxInt64 / 2)
+
+	strategy, err := NewStrategy(types.DistributionSettings{Strategy: StrategyWeighted, Weights: weights}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	distribution, err := strategy.Distribute(testProjects(2), totalVotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkDistribution(t, distribution, totalVotes)
+
+	// 1 vote each, the rest split 1 : MaxWeight, the lighter share rounds either way
+	lightShare := (totalVotes-2)/(MaxWeight+1) + 1
+
+	for _, data := range distribution {
+		if data.ProjectID == "project-2" && data.VotesAmount != lightShare && data.VotesAmount != lightShare+1 {
+			t.Errorf("project-2 got %d votes, want about %d", data.VotesAmount, lightShare)
+		}
+	}
+}
+
+func TestNewStrategyRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings types.DistributionSettings
+	}{
+		{"unknown strategy", types.DistributionSettings{Strategy: "lottery"}},
+		{"weighted without weights", types.DistributionSettings{Strategy: StrategyWeighted}},
+		{"zero weight", types.DistributionSettings{Strategy: StrategyWeighted, Weights: map[string]int64{"project-1": 0}}},
+		{"weight above the bound", types.DistributionSettings{Strategy: StrategyWeighted, Weights: map[string]int64{"project-1": MaxWeight + 1}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewStrategy(test.settings, nil); err == nil {
+				t.Errorf("NewStrategy accepted %+v", test.settings)
+			}
+		})
+	}
+}
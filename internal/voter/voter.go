@@ -1,160 +1,395 @@
 package voter
 
 import (
+	"context"
 	"fmt"
-	"github.com/ethereum/go-ethereum/accounts"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
 	log "github.com/sirupsen/logrus"
+	"main/internal/projectFilter"
+	"main/internal/report"
 	"main/internal/retroActions"
+	"main/internal/runState"
 	"main/internal/util"
+	"main/internal/votePlan"
+	"main/internal/voterConfirmer"
+	"main/pkg/global"
 	"main/pkg/types"
 	"math/rand"
+	"time"
 )
 
-func generateDistribution(
-	projects []retroActions.ProjectData,
-	totalVotes int64,
-) []DistributionData {
-	if len(projects) == 0 || totalVotes <= 0 {
-		return nil
+var (
+	distributionSeed int64
+	allocationPlan   *votePlan.Plan
+	projectsFilter   *projectFilter.Filter
+)
+
+// InitPlan makes DoVotes follow the plan file instead of the distribution strategy. The votes of a resumed
+// run were checked when it started and are partly cast, so only its projects are checked again
+func InitPlan(path string, resumed bool) error {
+	var err error
+
+	if projectsFilter, err = projectFilter.FromSettings(); err != nil {
+		return err
 	}
 
-	// Генерация случайного количества проектов от 5 до 14
-	randomMax := rand.Intn(10) + 5
-	numProjects := randomMax
-	if len(projects) < randomMax {
-		numProjects = len(projects)
+	var availableVotes votePlan.AvailableVotes
+
+	if !resumed {
+		availableVotes = func(votesData *retroActions.GetVotesResponse) int64 {
+			return votesData.Data.TotalEligibleVotes - votesData.Data.UsedVotes
+		}
 	}
 
-	if numProjects == 0 {
-		numProjects = 1
+	plan, err := votePlan.Prepare(context.Background(), path, global.AccountsList, projectsFilter, availableVotes)
+
+	if err != nil {
+		return err
 	}
 
-	selectedProjects := rand.Perm(len(projects))[:numProjects]
-	distribution := make([]DistributionData, 0, numProjects)
-	remainingVotes := totalVotes
+	allocationPlan = plan
+	log.Printf("Loaded Allocation Plan %s: %d Entries", path, len(plan.Entries))
 
-	// Распределяем голоса
-	for i, projectIndex := range selectedProjects {
-		var votes int64
-		if i == numProjects-1 {
-			// Последний проект получает все оставшиеся голоса
-			votes = remainingVotes
-		} else {
-			// Генерируем случайное число голосов, не превышающее оставшихся
-			maxVotes := remainingVotes / int64(numProjects-i)
-			if maxVotes > 1 {
-				votes = rand.Int63n(maxVotes) + 1
-			} else {
-				votes = 1
-			}
-		}
+	return nil
+}
 
-		// Проверяем, чтобы не перерасходовать
-		if votes > remainingVotes {
-			votes = remainingVotes
-		}
-		remainingVotes -= votes
+// InitDistribution checks the configured strategy and fixes the run seed; every account seed is derived from it
+func InitDistribution() error {
+	var err error
+
+	if projectsFilter, err = projectFilter.FromSettings(); err != nil {
+		return err
+	}
+
+	if _, err = NewStrategy(global.Settings.Distribution, nil); err != nil {
+		return err
+	}
+
+	distributionSeed = global.Settings.Distribution.Seed
+	if distributionSeed == 0 {
+		distributionSeed = time.Now().UnixNano()
+	}
+
+	log.Printf("Distribution Strategy: %s | Seed: %d (set distribution.seed to reproduce this run)",
+		global.Settings.Distribution.Strategy, distributionSeed)
+
+	return nil
+}
+
+func strategyDistribution(
+	accountData types.AccountData,
+	projectsList []retroActions.ProjectData,
+	availableVotes int64,
+) ([]DistributionData, error) {
+	accountSeed := AccountSeed(distributionSeed, accountData.AccountAddress.String())
+	strategy, err := NewStrategy(global.Settings.Distribution, rand.New(rand.NewSource(accountSeed)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return strategy.Distribute(projectsList, availableVotes)
+}
+
+// planDistribution validates the whole account plan before any vote is sent
+func planDistribution(
+	planEntries []votePlan.Entry,
+	projectsList []retroActions.ProjectData,
+	availableVotes int64,
+) ([]DistributionData, error) {
+	allocations, err := votePlan.Resolve(planEntries, projectsList, projectsFilter, availableVotes)
 
+	if err != nil {
+		return nil, err
+	}
+
+	distribution := make([]DistributionData, 0, len(allocations))
+	for _, allocation := range allocations {
 		distribution = append(distribution, DistributionData{
-			ProjectID:   projects[projectIndex].ID,
-			VotesAmount: votes,
+			ProjectID:   allocation.ProjectID,
+			VotesAmount: allocation.Votes,
 		})
 	}
 
-	// Проверка, если оставшиеся голоса не равны нулю (подстраховка)
-	if remainingVotes != 0 {
-		for i := range distribution {
-			if remainingVotes == 0 {
-				break
-			}
-			distribution[i].VotesAmount++
-			remainingVotes--
-		}
+	return distribution, nil
+}
+
+// planVotes reports the requests DoVotes would send, the new vote IDs are unknown until the votes exist
+func planVotes(
+	accountData types.AccountData,
+	distribution []DistributionData,
+	votesData *retroActions.GetVotesResponse,
+	accountReport *report.AccountReport,
+) {
+	for i, data := range distribution {
+		accountReport.AddPlanned(report.PlannedRequest{
+			Action:    report.PlannedVote,
+			ProjectID: data.ProjectID,
+			Votes:     data.VotesAmount,
+		})
+		log.Printf("%s | Dry Run | [%d/%d] | Would Vote to %s: %d Votes", accountData.AccountAddress.String(),
+			i+1, len(distribution), data.ProjectID, data.VotesAmount)
+	}
+
+	pendingIDs := votesData.NotConfirmedIDs()
+
+	if len(distribution) == 0 && len(pendingIDs) == 0 {
+		return
 	}
 
-	return distribution
+	accountReport.AddPlanned(report.PlannedRequest{
+		Action:  report.PlannedConfirm,
+		Votes:   int64(len(distribution) + len(pendingIDs)),
+		VoteIDs: pendingIDs,
+	})
+	log.Printf("%s | Dry Run | Would Approve %d New And %d Already Pending Votes",
+		accountData.AccountAddress.String(), len(distribution), len(pendingIDs))
 }
 
 func DoVotes(
+	ctx context.Context,
 	accountData types.AccountData,
 	accountProxy string,
+	accountReport *report.AccountReport,
 ) error {
-	client := util.GetClient(accountProxy)
+	address := accountData.AccountAddress.String()
+	state := runState.Get(address)
 
-	signText := retroActions.GetSignText(client, accountData)
-	signature, err := crypto.Sign(accounts.TextHash([]byte(signText)), accountData.PrivateKey)
+	if state.Step != runState.StepNone {
+		log.Printf("%s | Resuming After Step: %s", address, state.Step)
+	}
+
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
+
+	err := client.Authorize(ctx)
 
 	if err != nil {
-		return fmt.Errorf("%s | Failed to sign auth message: %s", accountData.AccountAddress.String(), err)
+		return err
 	}
 
-	signature[64] += 27
-	signedMessage := hexutil.Encode(signature)
+	if err = runState.MarkStep(address, runState.StepAuth); err != nil {
+		return err
+	}
+
+	log.Printf("%s | Successfully Authorized", address)
 
-	accessToken, refreshToken, err := retroActions.DoAuth(client, accountData, signedMessage)
+	votesData, err := client.GetVotes(ctx)
 
 	if err != nil {
 		return err
 	}
 
-	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
+	accountReport.SetBallot(votesData)
 
-	votesData := retroActions.GetVotes(client, accountData, accessToken, refreshToken)
+	// a shutdown request is honoured between steps only: once casting starts, the votes are cast
+	// and confirmed, so an interrupted run never leaves a partly cast, unconfirmed ballot
+	stepCtx := ctx
 
-	if votesData == nil {
-		log.Printf("%s | No Available Votes", accountData.AccountAddress.String())
-		return nil
+	if !state.Passed(runState.StepVote) {
+		if err = util.CheckInterrupted(ctx, accountData, "Voting"); err != nil {
+			return err
+		}
+
+		stepCtx = context.WithoutCancel(ctx)
+
+		voted, err := castVotes(stepCtx, client, accountData, votesData, state, accountReport)
+
+		if err != nil || !voted {
+			return err
+		}
+
+		if err = runState.MarkStep(address, runState.StepVote); err != nil {
+			return err
+		}
 	}
 
-	eligibleVotes := votesData.Data.TotalEligibleVotes
-	usedVotes := votesData.Data.UsedVotes
-	availableVotes := eligibleVotes - usedVotes
+	if !state.Passed(runState.StepConfirm) {
+		if err = util.CheckInterrupted(stepCtx, accountData, "Approving Votes"); err != nil {
+			return err
+		}
 
-	if availableVotes <= 0 {
-		log.Printf("%s | No Available Votes", accountData.AccountAddress.String())
-		return nil
+		votesData, err = client.GetVotes(stepCtx)
+
+		if err != nil {
+			return err
+		}
+
+		confirmedVotes, err := voterConfirmer.ConfirmPending(stepCtx, client, accountData, votesData)
+
+		if err != nil {
+			return err
+		}
+
+		// a resumed run may have approved the votes right before it stopped
+		if confirmedVotes == 0 && !state.Passed(runState.StepVote) {
+			return fmt.Errorf("%s | No Not Confirmed Votes", address)
+		}
+
+		if err = runState.MarkStep(address, runState.StepConfirm); err != nil {
+			return err
+		}
+
+		accountReport.MarkConfirmed()
+		log.Printf("%s | Successfully Approved", address)
 	}
 
-	log.Printf("%s | Eligible Votes: %d | Already Used Votes: %d | Available Votes: %d",
-		accountData.AccountAddress.String(), eligibleVotes, usedVotes, availableVotes)
+	if err = util.CheckInterrupted(ctx, accountData, "Verifying Votes"); err != nil {
+		return err
+	}
+
+	votesData, err = client.GetVotes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.SetBallot(votesData)
+
+	if pendingIDs := votesData.NotConfirmedIDs(); len(pendingIDs) > 0 {
+		return fmt.Errorf("%s | %d Votes Are Still Not Confirmed", address, len(pendingIDs))
+	}
+
+	return runState.MarkStep(address, runState.StepVerify)
+}
+
+// castVotes returns false when the account has nothing to vote with, a resumed account casts
+// the saved distribution except the projects that are already on the ballot
+func castVotes(
+	ctx context.Context,
+	client *retroActions.Client,
+	accountData types.AccountData,
+	votesData *retroActions.GetVotesResponse,
+	state runState.AccountState,
+	accountReport *report.AccountReport,
+) (bool, error) {
+	address := accountData.AccountAddress.String()
+	distribution := make([]DistributionData, 0, len(state.Distribution))
+
+	for _, data := range state.Distribution {
+		distribution = append(distribution, DistributionData{ProjectID: data.ProjectID, VotesAmount: data.Votes})
+	}
+
+	if len(distribution) > 0 {
+		ballotProjects := map[string]bool{}
+		for _, currentVote := range votesData.Data.Votes {
+			ballotProjects[currentVote.Project.Id] = true
+		}
+
+		remaining := distribution[:0]
+		for _, data := range distribution {
+			if !ballotProjects[data.ProjectID] {
+				remaining = append(remaining, data)
+			}
+		}
+
+		log.Printf("%s | Resuming Saved Distribution: %d Of %d Votes Left To Cast",
+			address, len(remaining), len(state.Distribution))
+		distribution = remaining
+	} else {
+		var err error
+		var planned bool
 
-	projectsList := retroActions.GetProjectsList(client, accountData, accessToken, refreshToken)
-	distribution := generateDistribution(projectsList, availableVotes)
+		distribution, planned, err = newDistribution(ctx, client, accountData, votesData)
+
+		if err != nil || !planned {
+			return false, err
+		}
+
+		if global.DryRun {
+			planVotes(accountData, distribution, votesData, accountReport)
+			return false, nil
+		}
+
+		savedDistribution := make([]report.ProjectVotes, 0, len(distribution))
+		for _, data := range distribution {
+			savedDistribution = append(savedDistribution, report.ProjectVotes{ProjectID: data.ProjectID, Votes: data.VotesAmount})
+		}
+
+		if err = runState.SaveDistribution(address, savedDistribution); err != nil {
+			return false, err
+		}
+	}
+
+	failedVotes := &retroActions.BatchError{Address: address, Action: "Voting", Total: len(distribution)}
 
 	for i, data := range distribution {
-		err = retroActions.DoVote(client, accountData, accessToken, refreshToken,
-			data.ProjectID, data.VotesAmount)
+		err := client.DoVote(ctx, data.ProjectID, data.VotesAmount)
 
 		if err != nil {
+			if abortErr := failedVotes.Add(err); abortErr != nil {
+				return false, abortErr
+			}
+
 			log.Printf("%v", err)
 		} else {
-			log.Printf("%s | [%d/%d] | Successfully Voted to %s: %d Votes", accountData.AccountAddress.String(),
+			accountReport.AddVoteCast(data.ProjectID, data.VotesAmount)
+			log.Printf("%s | [%d/%d] | Successfully Voted to %s: %d Votes", address,
 				i+1, len(distribution), data.ProjectID, data.VotesAmount)
 		}
 	}
 
-	var notConfirmedVotes []string
-	votesData = retroActions.GetVotes(client, accountData, accessToken, refreshToken)
+	// the vote step stays open, a resumed run casts the saved distribution votes that are not on the ballot
+	if err := failedVotes.Err(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// newDistribution returns false when there is nothing to vote for
+func newDistribution(
+	ctx context.Context,
+	client *retroActions.Client,
+	accountData types.AccountData,
+	votesData *retroActions.GetVotesResponse,
+) ([]DistributionData, bool, error) {
+	var planEntries []votePlan.Entry
+
+	if allocationPlan != nil {
+		planEntries = allocationPlan.ForAccount(accountData)
 
-	for _, voteData := range votesData.Data.Votes {
-		if !voteData.IsConfirmed {
-			notConfirmedVotes = append(notConfirmedVotes, voteData.Id)
+		if len(planEntries) == 0 {
+			log.Printf("%s | Account Is Not In The Plan, Skipping", accountData.AccountAddress.String())
+			return nil, false, nil
 		}
 	}
 
-	if notConfirmedVotes == nil {
-		return fmt.Errorf("%s | No Not Confirmed Votes", accountData.AccountAddress.String())
+	eligibleVotes := votesData.Data.TotalEligibleVotes
+	usedVotes := votesData.Data.UsedVotes
+	availableVotes := eligibleVotes - usedVotes
+
+	if availableVotes <= 0 && allocationPlan == nil {
+		log.Printf("%s | No Available Votes", accountData.AccountAddress.String())
+		return nil, false, nil
 	}
 
-	err = retroActions.ApproveVotes(client, accountData, accessToken, refreshToken, notConfirmedVotes)
+	log.Printf("%s | Eligible Votes: %d | Already Used Votes: %d | Available Votes: %d",
+		accountData.AccountAddress.String(), eligibleVotes, usedVotes, availableVotes)
+
+	projectsList, err := client.GetSharedProjectsList(ctx)
 
 	if err != nil {
-		return fmt.Errorf("%s | Failed to approve votes: %s", accountData.AccountAddress.String(), err)
+		return nil, false, err
 	}
 
-	log.Printf("%s | Successfully Approved", accountData.AccountAddress.String())
+	var distribution []DistributionData
 
-	return nil
+	if allocationPlan != nil {
+		// the plan may name any project, Resolve reports those the filter rejects
+		distribution, err = planDistribution(planEntries, projectsList, availableVotes)
+	} else {
+		filteredProjects := projectsFilter.Apply(projectsList)
+		log.Printf("%s | %d Of %d Projects Pass The Project Filter",
+			accountData.AccountAddress.String(), len(filteredProjects), len(projectsList))
+
+		distribution, err = strategyDistribution(accountData, filteredProjects, availableVotes)
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("%s | Failed To Distribute Votes: %v", accountData.AccountAddress.String(), err)
+	}
+
+	log.Printf("%s | Votes Distributed Between %d Projects",
+		accountData.AccountAddress.String(), len(distribution))
+
+	return distribution, true, nil
 }
@@ -0,0 +1,45 @@
+package voterBackup
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"main/internal/ballotBackup"
+	"main/internal/report"
+	"main/internal/retroActions"
+	"main/internal/util"
+	"main/pkg/types"
+)
+
+func BackupBallots(
+	ctx context.Context,
+	accountData types.AccountData,
+	accountProxy string,
+	accountReport *report.AccountReport,
+) error {
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
+
+	err := client.Authorize(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
+
+	votesData, err := client.GetVotes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.SetBallot(votesData)
+
+	if err = ballotBackup.Add(accountData.AccountAddress.String(), votesData); err != nil {
+		return err
+	}
+
+	log.Printf("%s | Saved Ballot: %d Votes On %d Projects, %d Not Confirmed", accountData.AccountAddress.String(),
+		votesData.Data.UsedVotes, len(votesData.Data.Votes), len(votesData.NotConfirmedIDs()))
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package voterConfirmer
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"main/internal/report"
+	"main/internal/retroActions"
+	"main/internal/util"
+	"main/pkg/global"
+	"main/pkg/types"
+	"strings"
+)
+
+func ConfirmPending(
+	ctx context.Context,
+	client *retroActions.Client,
+	accountData types.AccountData,
+	votesData *retroActions.GetVotesResponse,
+) (int, error) {
+	notConfirmedVotes := votesData.NotConfirmedIDs()
+
+	if len(notConfirmedVotes) == 0 {
+		return 0, nil
+	}
+
+	err := client.ApproveVotes(ctx, notConfirmedVotes)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s | Failed to approve votes: %s", accountData.AccountAddress.String(), err)
+	}
+
+	return len(notConfirmedVotes), nil
+}
+
+func ConfirmVotes(
+	ctx context.Context,
+	accountData types.AccountData,
+	accountProxy string,
+	accountReport *report.AccountReport,
+) error {
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
+
+	err := client.Authorize(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
+
+	votesData, err := client.GetVotes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.SetBallot(votesData)
+
+	if global.DryRun {
+		pendingIDs := votesData.NotConfirmedIDs()
+
+		if len(pendingIDs) > 0 {
+			accountReport.AddPlanned(report.PlannedRequest{
+				Action:  report.PlannedConfirm,
+				Votes:   int64(len(pendingIDs)),
+				VoteIDs: pendingIDs,
+			})
+		}
+
+		log.Printf("%s | Dry Run | Would Approve %d Votes", accountData.AccountAddress.String(), len(pendingIDs))
+		return nil
+	}
+
+	pendingIDs := votesData.NotConfirmedIDs()
+
+	if len(pendingIDs) == 0 {
+		accountReport.MarkConfirmed()
+		log.Printf("%s | No Not Confirmed Votes", accountData.AccountAddress.String())
+		return nil
+	}
+
+	log.Printf("%s | Approving %d Not Confirmed Votes", accountData.AccountAddress.String(), len(pendingIDs))
+
+	if _, err = ConfirmPending(ctx, client, accountData, votesData); err != nil {
+		return err
+	}
+
+	if err = util.CheckInterrupted(ctx, accountData, "Verifying Votes"); err != nil {
+		return err
+	}
+
+	votesData, err = VerifyConfirmed(ctx, client, accountData, pendingIDs)
+
+	if votesData != nil {
+		accountReport.SetBallot(votesData)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.MarkConfirmed()
+	log.Printf("%s | Successfully Approved And Verified %d Votes", accountData.AccountAddress.String(), len(pendingIDs))
+
+	return nil
+}
+
+// VerifyConfirmed re-reads the ballot and fails when any of the approved votes is not confirmed there
+func VerifyConfirmed(
+	ctx context.Context,
+	client *retroActions.Client,
+	accountData types.AccountData,
+	approvedIDs []string,
+) (*retroActions.GetVotesResponse, error) {
+	votesData, err := client.GetVotes(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	confirmedIDs := map[string]bool{}
+	for _, voteData := range votesData.Data.Votes {
+		confirmedIDs[voteData.Id] = voteData.IsConfirmed
+	}
+
+	var notConfirmed []string
+	for _, id := range approvedIDs {
+		if !confirmedIDs[id] {
+			notConfirmed = append(notConfirmed, id)
+		}
+	}
+
+	if len(notConfirmed) > 0 {
+		return votesData, fmt.Errorf("%s | %d Of %d Approved Votes Are Still Not Confirmed: %s",
+			accountData.AccountAddress.String(), len(notConfirmed), len(approvedIDs), strings.Join(notConfirmed, ", "))
+	}
+
+	return votesData, nil
+}
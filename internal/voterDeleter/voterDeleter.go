@@ -1,57 +1,153 @@
 package voterDeleter
 
 import (
+	"context"
 	"fmt"
-	"github.com/ethereum/go-ethereum/accounts"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
 	log "github.com/sirupsen/logrus"
+	"main/internal/ballotBackup"
+	"main/internal/report"
 	"main/internal/retroActions"
 	"main/internal/util"
+	"main/pkg/global"
 	"main/pkg/types"
+	"strings"
 )
 
+// Selection limits the deletion, zero values select every vote
+type Selection struct {
+	Projects []string // project IDs or names
+	State    string   // "confirmed", "unconfirmed" or empty for both
+	MinVotes int64
+	MaxVotes int64
+}
+
+const (
+	StateConfirmed   = "confirmed"
+	StateUnconfirmed = "unconfirmed"
+)
+
+var selection Selection
+
+func InitSelection(newSelection Selection) error {
+	switch newSelection.State {
+	case "", StateConfirmed, StateUnconfirmed:
+	default:
+		return fmt.Errorf("unknown vote state %q, use %s or %s", newSelection.State, StateConfirmed, StateUnconfirmed)
+	}
+
+	if newSelection.MaxVotes > 0 && newSelection.MinVotes > newSelection.MaxVotes {
+		return fmt.Errorf("minimum vote count %d is above the maximum %d", newSelection.MinVotes, newSelection.MaxVotes)
+	}
+
+	selection = newSelection
+
+	return nil
+}
+
+func (s Selection) matches(projectID string, projectName string, isConfirmed bool, voteCount int64) bool {
+	switch {
+	case s.State == StateConfirmed && !isConfirmed:
+		return false
+	case s.State == StateUnconfirmed && isConfirmed:
+		return false
+	case s.MinVotes > 0 && voteCount < s.MinVotes:
+		return false
+	case s.MaxVotes > 0 && voteCount > s.MaxVotes:
+		return false
+	case len(s.Projects) == 0:
+		return true
+	}
+
+	for _, project := range s.Projects {
+		if project == projectID || strings.EqualFold(project, strings.TrimSpace(projectName)) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func DeleteVotes(
+	ctx context.Context,
 	accountData types.AccountData,
 	accountProxy string,
+	accountReport *report.AccountReport,
 ) error {
-	client := util.GetClient(accountProxy)
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
 
-	signText := retroActions.GetSignText(client, accountData)
-	signature, err := crypto.Sign(accounts.TextHash([]byte(signText)), accountData.PrivateKey)
+	err := client.Authorize(ctx)
 
 	if err != nil {
-		return fmt.Errorf("%s | Failed to sign auth message: %s", accountData.AccountAddress.String(), err)
+		return err
 	}
 
-	signature[64] += 27
-	signedMessage := hexutil.Encode(signature)
+	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
 
-	accessToken, refreshToken, err := retroActions.DoAuth(client, accountData, signedMessage)
+	votesData, err := client.GetVotes(ctx)
 
 	if err != nil {
 		return err
 	}
 
-	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
+	accountReport.SetBallot(votesData)
 
-	votesData := retroActions.GetVotes(client, accountData, accessToken, refreshToken)
+	var votesToDelete []retroActions.BallotVote
+	for _, currentVote := range votesData.Data.Votes {
+		if selection.matches(currentVote.Project.Id, currentVote.Project.Name, currentVote.IsConfirmed, currentVote.VoteCount) {
+			votesToDelete = append(votesToDelete, currentVote)
+		}
+	}
 
-	if votesData == nil {
-		log.Printf("%s | No Available Votes", accountData.AccountAddress.String())
+	if len(votesToDelete) == 0 {
+		log.Printf("%s | No Votes Match The Deletion Filter", accountData.AccountAddress.String())
 		return nil
 	}
 
-	for i, currentVote := range votesData.Data.Votes {
-		err = retroActions.DeleteVote(client, accountData, accessToken, refreshToken, currentVote.Project.Id)
+	if global.DryRun {
+		for i, currentVote := range votesToDelete {
+			accountReport.AddPlanned(report.PlannedRequest{
+				Action:    report.PlannedDelete,
+				ProjectID: currentVote.Project.Id,
+				Votes:     currentVote.VoteCount,
+			})
+			log.Printf("%s | Dry Run | [%d/%d] Would Delete Vote To %s (%s): %d Votes | Confirmed: %t",
+				accountData.AccountAddress.String(), i+1, len(votesToDelete), currentVote.Project.Id,
+				currentVote.Project.Name, currentVote.VoteCount, currentVote.IsConfirmed)
+		}
+
+		return nil
+	}
+
+	// the ballot must be on disk before the first change, the run can be killed at any request
+	if err = ballotBackup.Add(accountData.AccountAddress.String(), votesData); err != nil {
+		return err
+	}
+
+	failedDeletions := &retroActions.BatchError{
+		Address: accountData.AccountAddress.String(),
+		Action:  "Deleting Votes",
+		Total:   len(votesToDelete),
+	}
+
+	for i, currentVote := range votesToDelete {
+		if err = util.CheckInterrupted(ctx, accountData, "Deleting Votes"); err != nil {
+			return err
+		}
+
+		err = client.DeleteVote(ctx, currentVote.Project.Id)
 
 		if err != nil {
+			if abortErr := failedDeletions.Add(err); abortErr != nil {
+				return abortErr
+			}
+
 			log.Printf("%v", err)
 		} else {
+			accountReport.AddVoteDeleted(currentVote.Project.Id, currentVote.VoteCount)
 			log.Printf("%s | [%d/%d] Successfully Deleted Vote To %s",
-				accountData.AccountAddress.String(), i+1, len(votesData.Data.Votes), currentVote.Project.Id)
+				accountData.AccountAddress.String(), i+1, len(votesToDelete), currentVote.Project.Id)
 		}
 	}
 
-	return nil
+	return failedDeletions.Err()
 }
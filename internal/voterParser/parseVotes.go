@@ -1,33 +1,66 @@
 package voterParser
 
 import (
+	"context"
 	"fmt"
-	"github.com/ethereum/go-ethereum/accounts"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
 	log "github.com/sirupsen/logrus"
+	"main/internal/report"
 	"main/internal/retroActions"
 	"main/internal/util"
 	"main/pkg/types"
 	util2 "main/pkg/util"
+	"regexp"
 )
 
-func ParseVotes(
-	accountData types.AccountData,
-	accountProxy string,
+var (
+	addressesWriter      *util2.UniqueLineWriter
+	accountsExportWriter *util2.UniqueLineWriter
+	privateKeyLineRegex  = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+)
+
+// InitOutputs opens the parser outputs, accountsExportPath is optional and receives key material
+func InitOutputs(
+	addressesPath string,
+	accountsExportPath string,
 ) error {
-	client := util.GetClient(accountProxy)
-	signText := retroActions.GetSignText(client, accountData)
-	signature, err := crypto.Sign(accounts.TextHash([]byte(signText)), accountData.PrivateKey)
+	var err error
 
+	addressesWriter, err = util2.NewUniqueLineWriter(addressesPath, 0644)
 	if err != nil {
-		return fmt.Errorf("%s | Failed to sign auth message: %s", accountData.AccountAddress.String(), err)
+		return fmt.Errorf("error when opening %s: %v", addressesPath, err)
+	}
+
+	// addresses are never appended next to the keys older versions wrote, the file must be moved first
+	for _, line := range addressesWriter.Lines() {
+		if privateKeyLineRegex.MatchString(line) {
+			if err = addressesWriter.Restrict(0600); err != nil {
+				return fmt.Errorf("error when restricting %s: %v", addressesPath, err)
+			}
+
+			return fmt.Errorf("%s contains private keys written by an older version, access is restricted "+
+				"to the owner; move it out of the working directory (or delete it) and run parse again", addressesPath)
+		}
 	}
 
-	signature[64] += 27
-	signedMessage := hexutil.Encode(signature)
+	if accountsExportPath != "" {
+		accountsExportWriter, err = util2.NewUniqueLineWriter(accountsExportPath, 0600)
+		if err != nil {
+			return fmt.Errorf("error when opening %s: %v", accountsExportPath, err)
+		}
+	}
 
-	accessToken, refreshToken, err := retroActions.DoAuth(client, accountData, signedMessage)
+	return nil
+}
+
+func ParseVotes(
+	ctx context.Context,
+	accountData types.AccountData,
+	accountProxy string,
+	accountReport *report.AccountReport,
+) error {
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
+
+	err := client.Authorize(ctx)
 
 	if err != nil {
 		return err
@@ -35,13 +68,14 @@ func ParseVotes(
 
 	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
 
-	votesData := retroActions.GetVotes(client, accountData, accessToken, refreshToken)
+	votesData, err := client.GetVotes(ctx)
 
-	if votesData == nil {
-		log.Printf("%s | No Available Votes", accountData.AccountAddress.String())
-		return nil
+	if err != nil {
+		return err
 	}
 
+	accountReport.SetBallot(votesData)
+
 	eligibleVotes := votesData.Data.TotalEligibleVotes
 	usedVotes := votesData.Data.UsedVotes
 	availableVotes := eligibleVotes - usedVotes
@@ -54,9 +88,27 @@ func ParseVotes(
 	log.Printf("%s | Eligible Votes: %d | Already Used Votes: %d | Available Votes: %d",
 		accountData.AccountAddress.String(), eligibleVotes, usedVotes, availableVotes)
 
-	if eligibleVotes > 0 {
-		util2.AppendFile("accounts_with_votes.txt",
-			fmt.Sprintf("%s\n", accountData.PrivateKeyHex))
+	if addressesWriter != nil {
+		line := accountData.AccountAddress.String()
+		if accountData.Index > 0 {
+			line = fmt.Sprintf("%s | line %d", line, accountData.Index)
+		}
+
+		err = addressesWriter.WriteLine(line)
+
+		if err != nil {
+			return fmt.Errorf("%s | Failed to save account address: %s", accountData.AccountAddress.String(), err)
+		}
+	}
+
+	if accountsExportWriter != nil && accountData.PrivateKeyHex == "" {
+		log.Warnf("%s | The Key Is Not Held By This Process, Not Exported", accountData.AccountAddress.String())
+	} else if accountsExportWriter != nil {
+		err = accountsExportWriter.WriteLine(accountData.PrivateKeyHex)
+
+		if err != nil {
+			return fmt.Errorf("%s | Failed to export account: %s", accountData.AccountAddress.String(), err)
+		}
 	}
 
 	return nil
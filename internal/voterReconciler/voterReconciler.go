@@ -0,0 +1,311 @@
+package voterReconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"main/internal/ballotBackup"
+	"main/internal/projectFilter"
+	"main/internal/report"
+	"main/internal/retroActions"
+	"main/internal/util"
+	"main/internal/votePlan"
+	"main/internal/voterConfirmer"
+	"main/pkg/global"
+	"main/pkg/types"
+)
+
+var (
+	desiredPlan    *votePlan.Plan
+	projectsFilter *projectFilter.Filter
+)
+
+type ballotChanges struct {
+	deletions []report.ProjectVotes
+	casts     []report.ProjectVotes
+	pending   []string // unconfirmed votes that are kept as they are
+}
+
+// InitPlan loads the desired per-account allocation the ballots are converged to
+func InitPlan(path string) error {
+	if path == "" {
+		return errors.New("reconcile requires a plan file, pass it with -plan")
+	}
+
+	var err error
+
+	if projectsFilter, err = projectFilter.FromSettings(); err != nil {
+		return err
+	}
+
+	// Проценты в желаемом состоянии считаются от всех голосов аккаунта, а не от оставшихся
+	plan, err := votePlan.Prepare(context.Background(), path, global.AccountsList, projectsFilter,
+		func(votesData *retroActions.GetVotesResponse) int64 {
+			return votesData.Data.TotalEligibleVotes
+		})
+
+	if err != nil {
+		return err
+	}
+
+	desiredPlan = plan
+	log.Printf("Loaded Desired Allocation %s: %d Entries", path, len(plan.Entries))
+
+	return nil
+}
+
+// diffBallot compares the ballot with the desired allocation. Only projects that are no longer wanted are
+// deleted, a vote with a different count is cast again with the new count, which replaces the old one.
+// Casts that lower a count go first, so the votes they free are available for the rest
+func diffBallot(
+	votesData *retroActions.GetVotesResponse,
+	allocations []votePlan.Allocation,
+) ballotChanges {
+	var changes ballotChanges
+	desiredVotes := make(map[string]int64, len(allocations))
+
+	for _, allocation := range allocations {
+		desiredVotes[allocation.ProjectID] = allocation.Votes
+	}
+
+	currentVotes := make(map[string]int64, len(votesData.Data.Votes))
+
+	for _, currentVote := range votesData.Data.Votes {
+		projectID := currentVote.Project.Id
+		currentVotes[projectID] = currentVote.VoteCount
+
+		if votes, ok := desiredVotes[projectID]; !ok {
+			changes.deletions = append(changes.deletions, report.ProjectVotes{ProjectID: projectID, Votes: currentVote.VoteCount})
+		} else if votes == currentVote.VoteCount && !currentVote.IsConfirmed {
+			changes.pending = append(changes.pending, currentVote.Id)
+		}
+	}
+
+	var raisedCasts []report.ProjectVotes
+
+	for _, allocation := range allocations {
+		votes, ok := currentVotes[allocation.ProjectID]
+		cast := report.ProjectVotes{ProjectID: allocation.ProjectID, Votes: allocation.Votes}
+
+		switch {
+		case ok && votes == allocation.Votes:
+		case ok && votes > allocation.Votes:
+			changes.casts = append(changes.casts, cast)
+		default:
+			raisedCasts = append(raisedCasts, cast)
+		}
+	}
+
+	changes.casts = append(changes.casts, raisedCasts...)
+
+	return changes
+}
+
+func Reconcile(
+	ctx context.Context,
+	accountData types.AccountData,
+	accountProxy string,
+	accountReport *report.AccountReport,
+) error {
+	planEntries := desiredPlan.ForAccount(accountData)
+
+	if len(planEntries) == 0 {
+		log.Printf("%s | Account Is Not In The Plan, Skipping", accountData.AccountAddress.String())
+		return nil
+	}
+
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
+
+	err := client.Authorize(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
+
+	votesData, err := client.GetVotes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.SetBallot(votesData)
+
+	projectsList, err := client.GetSharedProjectsList(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// Проценты в желаемом состоянии считаются от всех голосов аккаунта, а не от оставшихся
+	allocations, err := votePlan.Resolve(planEntries, projectsList, projectsFilter, votesData.Data.TotalEligibleVotes)
+
+	if err != nil {
+		return fmt.Errorf("%s | Invalid Plan: %v", accountData.AccountAddress.String(), err)
+	}
+
+	return Converge(ctx, client, accountData, votesData, allocations, "The Plan", accountReport)
+}
+
+// Converge deletes, casts and confirms votes until the ballot holds exactly the allocations, target names
+// where they come from in the logs
+func Converge(
+	ctx context.Context,
+	client *retroActions.Client,
+	accountData types.AccountData,
+	votesData *retroActions.GetVotesResponse,
+	allocations []votePlan.Allocation,
+	target string,
+	accountReport *report.AccountReport,
+) error {
+	changes := diffBallot(votesData, allocations)
+
+	if len(changes.deletions) == 0 && len(changes.casts) == 0 && len(changes.pending) == 0 {
+		log.Printf("%s | Ballot Already Matches %s", accountData.AccountAddress.String(), target)
+		return nil
+	}
+
+	log.Printf("%s | To Delete: %d | To Cast: %d | Pending Confirmation: %d",
+		accountData.AccountAddress.String(), len(changes.deletions), len(changes.casts), len(changes.pending))
+
+	if global.DryRun {
+		planChanges(accountData, changes, accountReport)
+		return nil
+	}
+
+	// the ballot must be on disk before the first change, the run can be killed at any request
+	err := ballotBackup.Add(accountData.AccountAddress.String(), votesData)
+
+	if err != nil {
+		return err
+	}
+
+	err = applyChanges(ctx, client, accountData, changes, accountReport)
+
+	if err != nil {
+		return err
+	}
+
+	if err = util.CheckInterrupted(ctx, accountData, "Approving Votes"); err != nil {
+		return err
+	}
+
+	votesData, err = client.GetVotes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	confirmedVotes, err := voterConfirmer.ConfirmPending(ctx, client, accountData, votesData)
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.SetBallot(votesData)
+
+	if confirmedVotes > 0 {
+		accountReport.MarkConfirmed()
+		log.Printf("%s | Successfully Approved %d Votes", accountData.AccountAddress.String(), confirmedVotes)
+	}
+
+	if remaining := diffBallot(votesData, allocations); len(remaining.deletions) > 0 || len(remaining.casts) > 0 {
+		return fmt.Errorf("%s | Ballot Still Differs From %s (%d To Delete, %d To Cast), Run Again",
+			accountData.AccountAddress.String(), target, len(remaining.deletions), len(remaining.casts))
+	}
+
+	log.Printf("%s | Ballot Matches %s", accountData.AccountAddress.String(), target)
+
+	return nil
+}
+
+// applyChanges deletes first, so the freed votes are available for the casts
+func applyChanges(
+	ctx context.Context,
+	client *retroActions.Client,
+	accountData types.AccountData,
+	changes ballotChanges,
+	accountReport *report.AccountReport,
+) error {
+	failedRequests := &retroActions.BatchError{
+		Address: accountData.AccountAddress.String(),
+		Action:  "Changing The Ballot",
+		Total:   len(changes.deletions) + len(changes.casts),
+	}
+
+	for i, deletion := range changes.deletions {
+		if err := util.CheckInterrupted(ctx, accountData, "Deleting Votes"); err != nil {
+			return err
+		}
+
+		if err := client.DeleteVote(ctx, deletion.ProjectID); err != nil {
+			if abortErr := failedRequests.Add(err); abortErr != nil {
+				return abortErr
+			}
+
+			log.Printf("%v", err)
+			continue
+		}
+
+		accountReport.AddVoteDeleted(deletion.ProjectID, deletion.Votes)
+		log.Printf("%s | [%d/%d] Successfully Deleted Vote To %s",
+			accountData.AccountAddress.String(), i+1, len(changes.deletions), deletion.ProjectID)
+	}
+
+	for i, cast := range changes.casts {
+		if err := util.CheckInterrupted(ctx, accountData, "Voting"); err != nil {
+			return err
+		}
+
+		if err := client.DoVote(ctx, cast.ProjectID, cast.Votes); err != nil {
+			if abortErr := failedRequests.Add(err); abortErr != nil {
+				return abortErr
+			}
+
+			log.Printf("%v", err)
+			continue
+		}
+
+		accountReport.AddVoteCast(cast.ProjectID, cast.Votes)
+		log.Printf("%s | [%d/%d] | Successfully Voted to %s: %d Votes", accountData.AccountAddress.String(),
+			i+1, len(changes.casts), cast.ProjectID, cast.Votes)
+	}
+
+	return failedRequests.Err()
+}
+
+func planChanges(
+	accountData types.AccountData,
+	changes ballotChanges,
+	accountReport *report.AccountReport,
+) {
+	for _, deletion := range changes.deletions {
+		accountReport.AddPlanned(report.PlannedRequest{
+			Action:    report.PlannedDelete,
+			ProjectID: deletion.ProjectID,
+			Votes:     deletion.Votes,
+		})
+		log.Printf("%s | Dry Run | Would Delete Vote To %s: %d Votes",
+			accountData.AccountAddress.String(), deletion.ProjectID, deletion.Votes)
+	}
+
+	for _, cast := range changes.casts {
+		accountReport.AddPlanned(report.PlannedRequest{
+			Action:    report.PlannedVote,
+			ProjectID: cast.ProjectID,
+			Votes:     cast.Votes,
+		})
+		log.Printf("%s | Dry Run | Would Vote to %s: %d Votes",
+			accountData.AccountAddress.String(), cast.ProjectID, cast.Votes)
+	}
+
+	accountReport.AddPlanned(report.PlannedRequest{
+		Action:  report.PlannedConfirm,
+		Votes:   int64(len(changes.casts) + len(changes.pending)),
+		VoteIDs: changes.pending,
+	})
+	log.Printf("%s | Dry Run | Would Approve %d New And %d Already Pending Votes",
+		accountData.AccountAddress.String(), len(changes.casts), len(changes.pending))
+}
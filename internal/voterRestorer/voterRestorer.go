@@ -0,0 +1,131 @@
+package voterRestorer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"main/internal/ballotBackup"
+	"main/internal/report"
+	"main/internal/retroActions"
+	"main/internal/util"
+	"main/internal/votePlan"
+	"main/internal/voterReconciler"
+	"main/pkg/global"
+	"main/pkg/types"
+	"strings"
+)
+
+var savedBallots *ballotBackup.Backup
+
+// InitBackup loads the ballots to restore, the backup must belong to the current round
+func InitBackup(path string) error {
+	if path == "" {
+		return errors.New("restore requires a ballot backup, pass it with -backup")
+	}
+
+	backup, err := ballotBackup.Load(path)
+
+	if err != nil {
+		return err
+	}
+
+	if backup.RoundID != global.Const.RoundID {
+		return fmt.Errorf("backup %s was made for round %s, the current round is %s",
+			path, backup.RoundID, global.Const.RoundID)
+	}
+
+	savedBallots = backup
+	log.Printf("Loaded Ballot Backup %s (%s, %s): %d Accounts", path, backup.Mode,
+		backup.CreatedAt.Format("2006-01-02 15:04:05"), len(backup.Accounts))
+
+	return nil
+}
+
+// savedAllocations turns the saved votes back into allocations and rejects projects that were removed since
+func savedAllocations(
+	accountBallot *ballotBackup.AccountBallot,
+	projects []retroActions.ProjectData,
+	availableVotes int64,
+) ([]votePlan.Allocation, error) {
+	projectsByID := make(map[string]retroActions.ProjectData, len(projects))
+	for _, project := range projects {
+		projectsByID[project.ID] = project
+	}
+
+	var problems []string
+	var totalVotes int64
+	allocations := make([]votePlan.Allocation, 0, len(accountBallot.Votes))
+
+	for _, vote := range accountBallot.Votes {
+		if project, ok := projectsByID[vote.ProjectID]; !ok || project.IsDeleted {
+			problems = append(problems, fmt.Sprintf("project %s (%s) is no longer in the round", vote.ProjectID, vote.ProjectName))
+			continue
+		}
+
+		totalVotes += vote.Votes
+		allocations = append(allocations, votePlan.Allocation{
+			ProjectID:   vote.ProjectID,
+			ProjectName: vote.ProjectName,
+			Votes:       vote.Votes,
+		})
+	}
+
+	if totalVotes > availableVotes {
+		problems = append(problems, fmt.Sprintf("backup holds %d votes, but only %d are available", totalVotes, availableVotes))
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+
+	return allocations, nil
+}
+
+// RestoreVotes brings the ballot back to the saved one: votes missing from the backup are deleted,
+// saved votes are cast again and every vote is confirmed
+func RestoreVotes(
+	ctx context.Context,
+	accountData types.AccountData,
+	accountProxy string,
+	accountReport *report.AccountReport,
+) error {
+	accountBallot := savedBallots.ForAccount(accountData.AccountAddress.String())
+
+	if accountBallot == nil {
+		log.Printf("%s | Account Is Not In The Backup, Skipping", accountData.AccountAddress.String())
+		return nil
+	}
+
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
+
+	err := client.Authorize(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%s | Successfully Authorized", accountData.AccountAddress.String())
+
+	votesData, err := client.GetVotes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.SetBallot(votesData)
+
+	projectsList, err := client.GetSharedProjectsList(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	allocations, err := savedAllocations(accountBallot, projectsList, votesData.Data.TotalEligibleVotes)
+
+	if err != nil {
+		return fmt.Errorf("%s | Cannot Restore Ballot: %v", accountData.AccountAddress.String(), err)
+	}
+
+	return voterReconciler.Converge(ctx, client, accountData, votesData, allocations, "The Backup", accountReport)
+}
@@ -0,0 +1,46 @@
+package voterStatus
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"main/internal/report"
+	"main/internal/retroActions"
+	"main/internal/util"
+	"main/pkg/types"
+)
+
+func ShowStatus(
+	ctx context.Context,
+	accountData types.AccountData,
+	accountProxy string,
+	accountReport *report.AccountReport,
+) error {
+	client := retroActions.NewClient(util.GetClient(accountProxy), accountData)
+
+	err := client.Authorize(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	votesData, err := client.GetVotes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	accountReport.SetBallot(votesData)
+
+	eligibleVotes := votesData.Data.TotalEligibleVotes
+	usedVotes := votesData.Data.UsedVotes
+
+	log.Printf("%s | Eligible Votes: %d | Already Used Votes: %d | Available Votes: %d | Votes On Ballot: %d",
+		accountData.AccountAddress.String(), eligibleVotes, usedVotes, eligibleVotes-usedVotes, len(votesData.Data.Votes))
+
+	for _, voteData := range votesData.Data.Votes {
+		log.Printf("%s | %s (%s): %d Votes | Confirmed: %t", accountData.AccountAddress.String(),
+			voteData.Project.Name, voteData.Project.Id, voteData.VoteCount, voteData.IsConfirmed)
+	}
+
+	return nil
+}
@@ -4,5 +4,33 @@ import "main/pkg/types"
 
 var (
 	AccountsList []types.AccountData
+	DryRun       bool // mutating requests are computed and reported, but never sent
 	Const        types.ConstStruct
+	Settings     = types.SettingsStruct{
+		Retry: types.RetrySettings{
+			MaxAttempts: 5,
+			BaseDelayMs: 1000,
+			MaxDelayMs:  30000,
+		},
+		Derivation: types.DerivationSettings{
+			Path:    "m/44'/60'/0'/0/{index}",
+			Indexes: "0",
+		},
+		Distribution: types.DistributionSettings{
+			Strategy:    "random",
+			MinProjects: 5,
+			MaxProjects: 14,
+		},
+		ProjectFilter: types.ProjectFilter{
+			Statuses: []string{"approved"},
+		},
+		SessionCache: types.SessionCacheSettings{
+			Path: "sessions.cache",
+		},
+		SignIn: types.SignInSettings{
+			Domains:       []string{"retro9000.avax.network", "api-retro-9000.avax.network"},
+			ChainIDs:      []int64{43114},
+			MaxAgeSeconds: 600,
+		},
+	}
 )
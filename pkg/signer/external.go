@@ -0,0 +1,203 @@
+package signer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const externalTimeout = 2 * time.Minute
+
+// External talks JSON-RPC 2.0 to a signer process over its stdin/stdout, one JSON object per line.
+// The process must answer eth_accounts with the addresses it holds and
+// personal_sign ["0x<message hex>", "0x<address>"] with a 65 byte hex signature
+type External struct {
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan rpcResponse
+	nextID    int
+	exited    chan struct{}
+	exitErr   error
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// StartExternal runs the command line (split on spaces), the process stderr is passed through
+func StartExternal(command string) (*External, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("empty signer command")
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error when starting signer %s: %v", fields[0], err)
+	}
+
+	e := &External{
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan rpcResponse, 16),
+		exited:    make(chan struct{}),
+	}
+
+	go e.readResponses(stdout)
+
+	return e, nil
+}
+
+func (e *External) readResponses(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var response rpcResponse
+
+		// a line that is not a response (a log line of the signer) is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil || response.ID == 0 {
+			continue
+		}
+
+		select {
+		case e.responses <- response:
+		default: // nobody waits for it, the caller gave up
+		}
+	}
+
+	e.exitErr = e.cmd.Wait()
+	close(e.exited)
+}
+
+func (e *External) call(method string, params []interface{}, result interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.nextID++
+	request := rpcRequest{JSONRPC: "2.0", ID: e.nextID, Method: method, Params: params}
+
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	if _, err = e.stdin.Write(append(requestBytes, '\n')); err != nil {
+		return fmt.Errorf("error when writing to signer: %v", err)
+	}
+
+	timeout := time.After(externalTimeout)
+
+	for {
+		select {
+		case response := <-e.responses:
+			if response.ID != request.ID {
+				continue // a late answer to a request that already timed out
+			}
+
+			if response.Error != nil {
+				return fmt.Errorf("signer rejected %s: %s (code %d)", method, response.Error.Message, response.Error.Code)
+			}
+
+			return json.Unmarshal(response.Result, result)
+		case <-e.exited:
+			return fmt.Errorf("signer exited: %v", e.exitErr)
+		case <-timeout:
+			return fmt.Errorf("signer did not answer %s in %s", method, externalTimeout)
+		}
+	}
+}
+
+// Accounts returns a signer for every address the process holds
+func (e *External) Accounts() ([]Signer, error) {
+	var addresses []string
+
+	if err := e.call("eth_accounts", []interface{}{}, &addresses); err != nil {
+		return nil, err
+	}
+
+	signers := make([]Signer, 0, len(addresses))
+
+	for _, address := range addresses {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("signer returned an invalid address %q", address)
+		}
+
+		signers = append(signers, &externalAccount{external: e, address: common.HexToAddress(address)})
+	}
+
+	return signers, nil
+}
+
+// Close ends the process by closing its stdin
+func (e *External) Close() error {
+	_ = e.stdin.Close()
+
+	select {
+	case <-e.exited:
+	case <-time.After(5 * time.Second):
+		_ = e.cmd.Process.Kill()
+		<-e.exited
+	}
+
+	return nil
+}
+
+type externalAccount struct {
+	external *External
+	address  common.Address
+}
+
+func (a *externalAccount) Address() common.Address {
+	return a.address
+}
+
+func (a *externalAccount) SignPersonalMessage(message []byte) ([]byte, error) {
+	var signatureHex string
+
+	err := a.external.call("personal_sign", []interface{}{hexutil.Encode(message), a.address.String()}, &signatureHex)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := hexutil.Decode(signatureHex)
+	if err != nil {
+		return nil, fmt.Errorf("signer returned an invalid signature: %v", err)
+	}
+
+	return normalizeSignature(a.address, message, signature)
+}
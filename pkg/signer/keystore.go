@@ -0,0 +1,155 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"sync"
+)
+
+// decryptSlots caps concurrent keystore decryption, a standard scrypt decryption takes about 256 MB
+var decryptSlots = make(chan struct{}, 2)
+
+// Keystore keeps the key encrypted, it is decrypted while the account is unlocked or for a single signature
+type Keystore struct {
+	keyJson    []byte
+	passphrase string
+	address    common.Address
+
+	mu      sync.Mutex
+	key     *keystore.Key
+	unlocks int
+}
+
+func decryptKey(keyJson []byte, passphrase string) (*keystore.Key, error) {
+	decryptSlots <- struct{}{}
+	defer func() { <-decryptSlots }()
+
+	return keystore.DecryptKey(keyJson, passphrase)
+}
+
+// NewKeystore reads the address of a V3 keystore file without decrypting it, the passphrase is checked
+// by Check or by the first Unlock. A file without an address is decrypted once to learn it
+func NewKeystore(keyJson []byte, passphrase string) (*Keystore, error) {
+	var keyFile struct {
+		Address string `json:"address"`
+	}
+
+	if err := json.Unmarshal(keyJson, &keyFile); err != nil {
+		return nil, fmt.Errorf("invalid keystore file: %v", err)
+	}
+
+	if keyFile.Address == "" {
+		key, err := decryptKey(keyJson, passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		defer clearKey(key)
+
+		return &Keystore{keyJson: keyJson, passphrase: passphrase, address: key.Address}, nil
+	}
+
+	if !common.IsHexAddress(keyFile.Address) {
+		return nil, fmt.Errorf("invalid keystore address %q", keyFile.Address)
+	}
+
+	return &Keystore{keyJson: keyJson, passphrase: passphrase, address: common.HexToAddress(keyFile.Address)}, nil
+}
+
+// Check decrypts the key once to verify the passphrase, the decrypted key is not kept
+func (s *Keystore) Check() error {
+	key, err := s.decrypt()
+	if err != nil {
+		return err
+	}
+
+	clearKey(key)
+
+	return nil
+}
+
+// decrypt also rejects a file whose key does not match the address it declares
+func (s *Keystore) decrypt() (*keystore.Key, error) {
+	key, err := decryptKey(s.keyJson, s.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error when decrypting keystore: %v", err)
+	}
+
+	if key.Address != s.address {
+		clearKey(key)
+		return nil, fmt.Errorf("keystore key belongs to %s, not to %s", key.Address.String(), s.address.String())
+	}
+
+	return key, nil
+}
+
+func (s *Keystore) Address() common.Address {
+	return s.address
+}
+
+// Unlock decrypts the key once and keeps it until the matching Lock
+func (s *Keystore) Unlock() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.unlocks == 0 {
+		key, err := s.decrypt()
+		if err != nil {
+			return err
+		}
+
+		s.key = key
+	}
+
+	s.unlocks++
+
+	return nil
+}
+
+// Lock clears the decrypted key once every Unlock is matched
+func (s *Keystore) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.unlocks == 0 {
+		return
+	}
+
+	s.unlocks--
+
+	if s.unlocks == 0 {
+		clearKey(s.key)
+		s.key = nil
+	}
+}
+
+func (s *Keystore) SignPersonalMessage(message []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key != nil {
+		return NewLocalKey(s.key.PrivateKey).SignPersonalMessage(message)
+	}
+
+	key, err := s.decrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	defer clearKey(key)
+
+	return NewLocalKey(key.PrivateKey).SignPersonalMessage(message)
+}
+
+func clearKey(key *keystore.Key) {
+	if key == nil {
+		return
+	}
+
+	secret := key.PrivateKey.D.Bits()
+	for i := range secret {
+		secret[i] = 0
+	}
+}
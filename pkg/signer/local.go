@@ -0,0 +1,33 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// LocalKey signs with a private key held in memory
+type LocalKey struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewLocalKey(key *ecdsa.PrivateKey) *LocalKey {
+	return &LocalKey{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (s *LocalKey) Address() common.Address {
+	return s.address
+}
+
+func (s *LocalKey) SignPersonalMessage(message []byte) ([]byte, error) {
+	signature, err := crypto.Sign(accounts.TextHash(message), s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	signature[64] += 27
+
+	return signature, nil
+}
@@ -0,0 +1,77 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs EIP-191 personal messages for one address, so the private key does not have to live in this process
+type Signer interface {
+	Address() common.Address
+	// SignPersonalMessage returns a 65 byte [R || S || V] signature with V 27 or 28
+	SignPersonalMessage(message []byte) ([]byte, error)
+}
+
+// Unlocker is a Signer that can keep its key decrypted while the account runs, every successful Unlock
+// must be followed by Lock
+type Unlocker interface {
+	Unlock() error
+	Lock()
+}
+
+// normalizeSignature accepts V as 0/1 or 27/28 and checks that the signature belongs to the address
+func normalizeSignature(
+	address common.Address,
+	message []byte,
+	signature []byte,
+) ([]byte, error) {
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("signature must be %d bytes, got %d", crypto.SignatureLength, len(signature))
+	}
+
+	signature = append([]byte(nil), signature...)
+
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+
+	if signature[64] > 1 {
+		return nil, fmt.Errorf("invalid signature recovery id %d", signature[64])
+	}
+
+	signature[64] += 27
+
+	if err := Verify(address, message, signature); err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// Verify recovers the signer of a personal message signature (V 27 or 28) and compares it with the address
+func Verify(
+	address common.Address,
+	message []byte,
+	signature []byte,
+) error {
+	if len(signature) != crypto.SignatureLength || signature[64] < 27 {
+		return errors.New("malformed signature")
+	}
+
+	recoverable := append([]byte(nil), signature...)
+	recoverable[64] -= 27
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash(message), recoverable)
+	if err != nil {
+		return fmt.Errorf("signature cannot be recovered: %v", err)
+	}
+
+	if recovered := crypto.PubkeyToAddress(*publicKey); recovered != address {
+		return fmt.Errorf("signature was made by %s", recovered.String())
+	}
+
+	return nil
+}
@@ -3,14 +3,86 @@ package types
 import (
 	"crypto/ecdsa"
 	"github.com/ethereum/go-ethereum/common"
+	"main/pkg/signer"
 )
 
 type AccountData struct {
-	PrivateKeyHex  string
-	PrivateKey     *ecdsa.PrivateKey
+	Index          int               // line number in the source accounts file
+	Label          string            // optional name from the accounts file, shared by all addresses of one mnemonic
+	PrivateKeyHex  string            // empty when the key is not held in memory (keystore, external signer)
+	PrivateKey     *ecdsa.PrivateKey // nil when the key is not held in memory
 	AccountAddress common.Address
+	Signer         signer.Signer
 }
 
 type ConstStruct struct {
 	RoundID string `json:"round_id"`
 }
+
+type SettingsStruct struct {
+	APIBaseURL    string               `json:"api_base_url"`
+	Retry         RetrySettings        `json:"retry"`
+	Derivation    DerivationSettings   `json:"derivation"`
+	Distribution  DistributionSettings `json:"distribution"`
+	ProjectFilter ProjectFilter        `json:"project_filter"`
+	SessionCache  SessionCacheSettings `json:"session_cache"`
+	SignIn        SignInSettings       `json:"sign_in"`
+	TLS           TLSSettings          `json:"tls"`
+}
+
+// TLSSettings extend certificate verification: CAFile is a PEM bundle added to the system roots,
+// SPKIPins are base64 SHA-256 hashes of public keys accepted for the API host
+type TLSSettings struct {
+	CAFile   string   `json:"ca_file"`
+	SPKIPins []string `json:"spki_pins"`
+}
+
+// SignInSettings restrict the sign-in message the accounts agree to sign, empty lists allow any value.
+// AllowPlainText signs a message that is not EIP-4361, such a message is only checked for foreign addresses
+type SignInSettings struct {
+	Domains        []string `json:"domains"`
+	ChainIDs       []int64  `json:"chain_ids"`
+	MaxAgeSeconds  int64    `json:"max_age_seconds"`
+	AllowPlainText bool     `json:"allow_plain_text_message"`
+}
+
+type SessionCacheSettings struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+}
+
+type RetrySettings struct {
+	MaxAttempts int   `json:"max_attempts"`
+	BaseDelayMs int64 `json:"base_delay_ms"`
+	MaxDelayMs  int64 `json:"max_delay_ms"`
+}
+
+type DerivationSettings struct {
+	Path       string `json:"path"`
+	Indexes    string `json:"indexes"`
+	Passphrase string `json:"passphrase"`
+}
+
+type DistributionSettings struct {
+	Strategy    string           `json:"strategy"`
+	Seed        int64            `json:"seed"`
+	MinProjects int              `json:"min_projects"`
+	MaxProjects int              `json:"max_projects"`
+	Weights     map[string]int64 `json:"weights"`
+}
+
+// ProjectFilter selects projects, zero values disable a condition. Allow and Deny hold
+// project IDs or regular expressions matched against the project name
+type ProjectFilter struct {
+	Categories        []string `json:"categories"`
+	ExcludeCategories []string `json:"exclude_categories"`
+	Statuses          []string `json:"statuses"`
+	MinRank           int      `json:"min_rank"`
+	MaxRank           int      `json:"max_rank"`
+	MinUniqueVoters   int      `json:"min_unique_voters"`
+	MaxUniqueVoters   int      `json:"max_unique_voters"`
+	MinTotalVotes     int64    `json:"min_total_votes"`
+	MaxTotalVotes     int64    `json:"max_total_votes"`
+	Allow             []string `json:"allow"`
+	Deny              []string `json:"deny"`
+}
@@ -4,12 +4,15 @@ import (
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	log "github.com/sirupsen/logrus"
 	"github.com/tyler-smith/go-bip32"
 	"github.com/tyler-smith/go-bip39"
+	"main/pkg/signer"
 	"main/pkg/types"
+	"strings"
 )
 
 func validateAndLog(valid bool, err error, inputType string, input string) bool {
@@ -20,33 +23,54 @@ func validateAndLog(valid bool, err error, inputType string, input string) bool
 	return true
 }
 
-func isMnemonic(input string) (bool, *ecdsa.PrivateKey, common.Address, error) {
+// isMnemonic derives at most limit keys, 0 derives every index of the range. It returns false only when
+// the input is not a mnemonic, an error for a valid mnemonic comes from the derivation settings
+func isMnemonic(input string, derivation types.DerivationSettings, limit int) (bool, []*ecdsa.PrivateKey, error) {
 	if !bip39.IsMnemonicValid(input) {
-		return false, nil, common.Address{}, errors.New("invalid mnemonic phrase")
+		return false, nil, errors.New("invalid mnemonic phrase")
 	}
 
-	seed, err := bip39.NewSeedWithErrorChecking(input, "")
+	seed, err := bip39.NewSeedWithErrorChecking(input, derivation.Passphrase)
 	if err != nil {
-		return false, nil, common.Address{}, err
+		return true, nil, err
 	}
 
 	masterKey, err := bip32.NewMasterKey(seed)
 	if err != nil {
-		return false, nil, common.Address{}, err
+		return true, nil, err
 	}
 
-	addressKey, err := deriveAddressKey(masterKey)
+	indexes, err := parseIndexRange(derivation.Indexes)
 	if err != nil {
-		return false, nil, common.Address{}, err
+		return true, nil, err
 	}
 
-	privateKey, err := crypto.ToECDSA(addressKey.Key)
-	if err != nil {
-		return false, nil, common.Address{}, err
+	if limit > 0 && len(indexes) > limit {
+		indexes = indexes[:limit]
 	}
 
-	address := crypto.PubkeyToAddress(privateKey.PublicKey)
-	return true, privateKey, address, nil
+	var privateKeys []*ecdsa.PrivateKey
+
+	for _, index := range indexes {
+		path, err := parseDerivationPath(derivation.Path, index)
+		if err != nil {
+			return true, nil, err
+		}
+
+		addressKey, err := deriveAddressKey(masterKey, path)
+		if err != nil {
+			return true, nil, fmt.Errorf("index %d: %v", index, err)
+		}
+
+		privateKey, err := crypto.ToECDSA(addressKey.Key)
+		if err != nil {
+			return true, nil, fmt.Errorf("index %d: %v", index, err)
+		}
+
+		privateKeys = append(privateKeys, privateKey)
+	}
+
+	return true, privateKeys, nil
 }
 
 func isPrivateKey(input string) (bool, *ecdsa.PrivateKey, common.Address, error) {
@@ -70,44 +94,88 @@ func isPrivateKey(input string) (bool, *ecdsa.PrivateKey, common.Address, error)
 	return true, privateKey, address, nil
 }
 
-func deriveAddressKey(masterKey *bip32.Key) (*bip32.Key, error) {
-	purpose, err := masterKey.NewChildKey(bip32.FirstHardenedChild + 44)
-	if err != nil {
-		return nil, err
-	}
-	coinType, err := purpose.NewChildKey(bip32.FirstHardenedChild + 60)
-	if err != nil {
-		return nil, err
-	}
-	account, err := coinType.NewChildKey(bip32.FirstHardenedChild)
-	if err != nil {
-		return nil, err
-	}
-	change, err := account.NewChildKey(0)
-	if err != nil {
-		return nil, err
+func deriveAddressKey(masterKey *bip32.Key, path []uint32) (*bip32.Key, error) {
+	key := masterKey
+
+	for _, childIndex := range path {
+		var err error
+
+		key, err = key.NewChildKey(childIndex)
+		if err != nil {
+			return nil, err
+		}
 	}
-	addressKey, err := change.NewChildKey(0)
-	if err != nil {
-		return nil, err
+
+	return key, nil
+}
+
+// parseAccountLine splits "secret;path=...;indexes=0-9;passphrase=...;label=..." into the secret,
+// its derivation settings and label
+func parseAccountLine(
+	lineNumber int,
+	line string,
+	derivation types.DerivationSettings,
+) (string, types.DerivationSettings, string) {
+	var label string
+	parts := strings.Split(line, ";")
+
+	for _, option := range parts[1:] {
+		key, value, found := strings.Cut(option, "=")
+		if !found {
+			log.Warnf("Line %d | Unknown account option: %s", lineNumber, option)
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "path":
+			derivation.Path = strings.TrimSpace(value)
+		case "indexes":
+			derivation.Indexes = strings.TrimSpace(value)
+		case "passphrase":
+			derivation.Passphrase = value
+		case "label":
+			label = strings.TrimSpace(value)
+		default:
+			log.Warnf("Line %d | Unknown account option: %s", lineNumber, key)
+		}
 	}
-	return addressKey, nil
+
+	return strings.TrimSpace(parts[0]), derivation, label
 }
 
-func GetAccounts(accountsListString []string) ([]types.AccountData, error) {
+// GetAccounts derives the accounts of every line, limit stops after that many accounts (0 - no limit)
+func GetAccounts(
+	accountsListString []string,
+	derivation types.DerivationSettings,
+	limit int,
+) ([]types.AccountData, error) {
 	var accounts []types.AccountData
 
-	for _, currentAccountData := range accountsListString {
+	for i, currentAccountLine := range accountsListString {
+		if limit > 0 && len(accounts) >= limit {
+			break
+		}
+
 		var valid bool
-		var privateKey *ecdsa.PrivateKey
-		var accountAddress common.Address
+		var privateKeys []*ecdsa.PrivateKey
 		var err error
 
+		currentAccountData, lineDerivation, label := parseAccountLine(i+1, currentAccountLine, derivation)
+
 		// Проверяем, является ли это мнемонической фразой
-		valid, privateKey, accountAddress, err = isMnemonic(currentAccountData)
+		valid, privateKeys, err = isMnemonic(currentAccountData, lineDerivation, limit-len(accounts))
+
+		// Мнемоника валидна, но не выводится - ошибка настроек derivation, а не приватный ключ
+		if valid && err != nil {
+			return nil, fmt.Errorf("line %d | failed to derive mnemonic accounts: %v", i+1, err)
+		}
+
 		if !valid {
 			// Если не является мнемонической фразой, проверяем, является ли это приватным ключом
-			valid, privateKey, accountAddress, err = isPrivateKey(currentAccountData)
+			var privateKey *ecdsa.PrivateKey
+
+			valid, privateKey, _, err = isPrivateKey(currentAccountData)
+			privateKeys = []*ecdsa.PrivateKey{privateKey}
 		}
 
 		if !valid {
@@ -116,12 +184,17 @@ func GetAccounts(accountsListString []string) ([]types.AccountData, error) {
 			continue
 		}
 
-		// Если валидно, добавляем аккаунт в список
-		accounts = append(accounts, types.AccountData{
-			PrivateKeyHex:  hex.EncodeToString(crypto.FromECDSA(privateKey)),
-			PrivateKey:     privateKey,
-			AccountAddress: accountAddress,
-		})
+		// Если валидно, добавляем аккаунты в список (из мнемоники может быть выведено несколько адресов)
+		for _, privateKey := range privateKeys {
+			accounts = append(accounts, types.AccountData{
+				Index:          i + 1,
+				Label:          label,
+				PrivateKeyHex:  hex.EncodeToString(crypto.FromECDSA(privateKey)),
+				PrivateKey:     privateKey,
+				AccountAddress: crypto.PubkeyToAddress(privateKey.PublicKey),
+				Signer:         signer.NewLocalKey(privateKey),
+			})
+		}
 	}
 
 	return accounts, nil
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	log "github.com/sirupsen/logrus"
+	"main/pkg/signer"
+	"main/pkg/types"
+	"os"
+	"path/filepath"
+)
+
+// GetKeystoreAccounts loads a single V3 keystore file or every keystore file in a directory,
+// limit stops after that many accounts (0 - no limit)
+func GetKeystoreAccounts(
+	keystorePath string,
+	passphrase string,
+	limit int,
+) ([]types.AccountData, error) {
+	info, err := os.Stat(keystorePath)
+	if err != nil {
+		return nil, fmt.Errorf("error when opening keystore: %v", err)
+	}
+
+	keyFiles := []string{keystorePath}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(keystorePath)
+		if err != nil {
+			return nil, fmt.Errorf("error when reading keystore directory: %v", err)
+		}
+
+		keyFiles = nil
+		for _, entry := range entries {
+			if entry.IsDir() || entry.Name()[0] == '.' {
+				continue
+			}
+
+			keyFiles = append(keyFiles, filepath.Join(keystorePath, entry.Name()))
+		}
+	}
+
+	if len(keyFiles) == 0 {
+		return nil, fmt.Errorf("no keystore files in %s", keystorePath)
+	}
+
+	var accounts []types.AccountData
+
+	for _, keyFile := range keyFiles {
+		if limit > 0 && len(accounts) >= limit {
+			break
+		}
+
+		keyJson, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error when reading keystore file %s: %v", keyFile, err)
+		}
+
+		// a file that cannot be read as a keystore is an error, skipping it would silently drop the account
+		keystoreSigner, err := signer.NewKeystore(keyJson, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%s | failed to load keystore file: %v", keyFile, err)
+		}
+
+		// scrypt is slow on purpose, so the passphrase is checked on the first file only,
+		// the other keys are decrypted when their accounts start
+		if len(accounts) == 0 {
+			if err = keystoreSigner.Check(); err != nil {
+				return nil, fmt.Errorf("%s | wrong keystore passphrase: %v", keyFile, err)
+			}
+		}
+
+		// the key stays encrypted, it is decrypted only to sign
+		accounts = append(accounts, types.AccountData{
+			AccountAddress: keystoreSigner.Address(),
+			Signer:         keystoreSigner,
+		})
+	}
+
+	return accounts, nil
+}
+
+// CreateKeystore encrypts the accounts into keystoreDir, accounts already in the directory are skipped
+func CreateKeystore(
+	keystoreDir string,
+	passphrase string,
+	accounts []types.AccountData,
+) (int, error) {
+	ks := keystore.NewKeyStore(keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+	created := 0
+
+	for _, account := range accounts {
+		if ks.HasAddress(account.AccountAddress) {
+			log.Printf("%s | Already In Keystore", account.AccountAddress.String())
+			continue
+		}
+
+		if _, err := ks.ImportECDSA(account.PrivateKey, passphrase); err != nil {
+			return created, fmt.Errorf("%s | Failed To Encrypt Account: %v", account.AccountAddress.String(), err)
+		}
+
+		created++
+	}
+
+	return created, nil
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"fmt"
+	"github.com/tyler-smith/go-bip32"
+	"strconv"
+	"strings"
+)
+
+// parseDerivationPath turns a template like m/44'/60'/0'/0/{index} into child indexes for the given address index
+func parseDerivationPath(template string, index uint32) ([]uint32, error) {
+	path := strings.ReplaceAll(template, "{index}", strconv.FormatUint(uint64(index), 10))
+	components := strings.Split(strings.TrimSpace(path), "/")
+
+	if len(components) < 2 || components[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path: %s", template)
+	}
+
+	childIndexes := make([]uint32, 0, len(components)-1)
+
+	for _, component := range components[1:] {
+		hardened := strings.HasSuffix(component, "'") || strings.HasSuffix(component, "h")
+		component = strings.TrimRight(component, "'h")
+
+		value, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path component %q in %s", component, template)
+		}
+
+		childIndex := uint32(value)
+		if hardened {
+			childIndex += bip32.FirstHardenedChild
+		}
+
+		childIndexes = append(childIndexes, childIndex)
+	}
+
+	return childIndexes, nil
+}
+
+// parseIndexRange accepts "0", "0-9" or a comma separated mix like "0,3,5-7"
+func parseIndexRange(indexRange string) ([]uint32, error) {
+	if strings.TrimSpace(indexRange) == "" {
+		return []uint32{0}, nil
+	}
+
+	var indexes []uint32
+
+	for _, part := range strings.Split(indexRange, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+
+		start, err := strconv.ParseUint(strings.TrimSpace(first), 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index range: %s", indexRange)
+		}
+
+		end := start
+		if isRange {
+			end, err = strconv.ParseUint(strings.TrimSpace(last), 10, 31)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid index range: %s", indexRange)
+			}
+		}
+
+		for index := start; index <= end; index++ {
+			indexes = append(indexes, uint32(index))
+		}
+	}
+
+	return indexes, nil
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+	"strings"
+)
+
+const KeystorePasswordEnv = "RETRO9000_KEYSTORE_PASSWORD"
+
+// ReadPassphrase takes the passphrase from the password file, then the env variable, then the terminal
+func ReadPassphrase(
+	passwordFile string,
+	prompt string,
+	confirm bool,
+) (string, error) {
+	if passwordFile != "" {
+		passwordBytes, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return "", fmt.Errorf("error when reading password file: %v", err)
+		}
+
+		return strings.TrimRight(string(passwordBytes), "\r\n"), nil
+	}
+
+	if passphrase, ok := os.LookupEnv(KeystorePasswordEnv); ok {
+		return passphrase, nil
+	}
+
+	passphrase, err := promptPassphrase(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		repeated, err := promptPassphrase("Repeat " + prompt)
+		if err != nil {
+			return "", err
+		}
+
+		if repeated != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+
+		if err != nil {
+			return "", fmt.Errorf("error when reading passphrase: %v", err)
+		}
+
+		return string(passwordBytes), nil
+	}
+
+	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && input == "" {
+		return "", fmt.Errorf("error when reading passphrase: %v", err)
+	}
+
+	return strings.TrimRight(input, "\r\n"), nil
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type UniqueLineWriter struct {
+	filePath string
+	perm     os.FileMode
+	lines    map[string]struct{}
+	mu       sync.Mutex
+}
+
+// NewUniqueLineWriter loads already written lines so repeated runs don't duplicate them,
+// the permissions of an existing file are only ever tightened to perm
+func NewUniqueLineWriter(filePath string, perm os.FileMode) (*UniqueLineWriter, error) {
+	writer := &UniqueLineWriter{
+		filePath: filePath,
+		perm:     perm,
+		lines:    map[string]struct{}{},
+	}
+
+	if _, err := os.Stat(filePath); err == nil {
+		if err = writer.Restrict(perm); err != nil {
+			return nil, err
+		}
+
+		lines, err := ReadFileByRows(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range lines {
+			writer.lines[line] = struct{}{}
+		}
+	}
+
+	return writer, nil
+}
+
+// Restrict removes every permission bit that is not in perm, permissions are never widened
+func (w *UniqueLineWriter) Restrict(perm os.FileMode) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.perm &= perm
+
+	info, err := os.Stat(w.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().Perm()&^perm == 0 {
+		return nil
+	}
+
+	if err = os.Chmod(w.filePath, info.Mode().Perm()&perm); err != nil {
+		return fmt.Errorf("error when changing file permissions: %v", err)
+	}
+
+	return nil
+}
+
+func (w *UniqueLineWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := make([]string, 0, len(w.lines))
+	for line := range w.lines {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func (w *UniqueLineWriter) WriteLine(line string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.lines[line]; ok {
+		return nil
+	}
+
+	file, err := os.OpenFile(w.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, w.perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = file.WriteString(line + "\n"); err != nil {
+		return err
+	}
+
+	w.lines[line] = struct{}{}
+
+	return nil
+}